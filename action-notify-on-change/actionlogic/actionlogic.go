@@ -8,25 +8,29 @@ import (
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/annotatedinfo"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/changetosend"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
 )
 
 type ActionLogic struct {
-	Fetcher annotatedinfo.Fetch
-	Sender  changetosend.Sender
-	Creator *changetosend.Creator
-	logger  logger.Logger
+	GhClient *ghclient.GhClient
+	Fetcher  annotatedinfo.Fetch
+	Sender   changetosend.Sender
+	Creator  *changetosend.Creator
+	logger   logger.Logger
 }
 
-func New(logger logger.Logger, fetcher annotatedinfo.Fetch, sender changetosend.Sender, creator *changetosend.Creator) *ActionLogic {
+func New(logger logger.Logger, ghClient *ghclient.GhClient, fetcher annotatedinfo.Fetch, sender changetosend.Sender, creator *changetosend.Creator) *ActionLogic {
 	return &ActionLogic{
-		Fetcher: fetcher,
-		Sender:  sender,
-		Creator: creator,
-		logger:  logger,
+		GhClient: ghClient,
+		Fetcher:  fetcher,
+		Sender:   sender,
+		Creator:  creator,
+		logger:   logger,
 	}
 }
 
 func (a *ActionLogic) Run(ctx context.Context) error {
+	defer a.GhClient.LogRateLimits()
 	a.logger.Infof("Fetching annotated info")
 	annotatedInfo, err := a.Fetcher.Populate(ctx)
 	if err != nil {
@@ -37,9 +41,17 @@ func (a *ActionLogic) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create changes: %w", err)
 	}
+	a.logger.Infof("Creating subscription changes")
+	subscriptionChanges, err := a.Creator.CreateSubscriptionChanges(ctx, annotatedInfo.ChangedFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription changes: %w", err)
+	}
 	a.logger.Infof("Sending messages")
 	if err := changetosend.SendMessagesInParallel(ctx, a.Sender, changes); err != nil {
 		return fmt.Errorf("failed to send messages in parallel: %w", err)
 	}
+	if err := changetosend.SendSubscriptionChangesInParallel(ctx, a.Sender, subscriptionChanges); err != nil {
+		return fmt.Errorf("failed to send subscription changes in parallel: %w", err)
+	}
 	return nil
 }
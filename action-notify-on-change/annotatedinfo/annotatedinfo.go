@@ -1,13 +1,20 @@
 package annotatedinfo
 
-import "context"
+import (
+	"context"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+)
 
 type AnnotatedInfo struct {
-	ChangedFiles []string
+	ChangedFiles []ghclient.ChangedFile
+	Title        string // Title of the pull request, or first line of the commit message
 	LinkToChange string
 	LinkToAuthor string
 	PrCreator    string
 	PrBase       string
+	PrHead       string
+	AuthorIsBot  bool
 }
 
 func (a *AnnotatedInfo) Populate(_ context.Context) (*AnnotatedInfo, error) {
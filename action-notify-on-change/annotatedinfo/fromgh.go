@@ -40,10 +40,13 @@ func (p *PopulateFromGh) Populate(ctx context.Context) (*AnnotatedInfo, error) {
 		}
 		return p.setCache(&AnnotatedInfo{
 			ChangedFiles: prInfo.ChangedFiles,
+			Title:        prInfo.PrTitle,
 			LinkToChange: prInfo.PrLink,
 			LinkToAuthor: prInfo.AuthorLink,
 			PrCreator:    prInfo.PrCreator,
 			PrBase:       prInfo.PrBase,
+			PrHead:       prInfo.PrHead,
+			AuthorIsBot:  prInfo.AuthorIsBot,
 		}), nil
 	}
 	p.logger.Infof("Appears to be a commit")
@@ -53,9 +56,11 @@ func (p *PopulateFromGh) Populate(ctx context.Context) (*AnnotatedInfo, error) {
 	}
 	return p.setCache(&AnnotatedInfo{
 		ChangedFiles: commitInfo.ChangedFiles,
+		Title:        commitInfo.Title,
 		LinkToChange: commitInfo.LinkToChange,
 		LinkToAuthor: commitInfo.AuthorLink,
 		PrCreator:    commitInfo.AuthorName,
+		AuthorIsBot:  commitInfo.AuthorIsBot,
 	}), nil
 }
 
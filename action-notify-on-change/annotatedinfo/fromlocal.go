@@ -0,0 +1,73 @@
+package annotatedinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+)
+
+// PopulateFromLocal reads the changed files, and what it can of the metadata, from the repository checked out in the
+// workspace, without calling the GitHub API
+type PopulateFromLocal struct {
+	repo   *localrepo.Repo
+	cfg    config.Config
+	logger logger.Logger
+	cache  *AnnotatedInfo
+}
+
+func NewFromLocal(cfg config.Config, repo *localrepo.Repo, logger logger.Logger) *PopulateFromLocal {
+	return &PopulateFromLocal{
+		repo:   repo,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+func (p *PopulateFromLocal) Populate(ctx context.Context) (*AnnotatedInfo, error) {
+	if p.cache != nil {
+		p.logger.Infof("Using cached annotated info")
+		return p.cache, nil
+	}
+	changedFiles, err := p.repo.ChangedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute changed files: %w", err)
+	}
+	head, err := p.repo.HeadCommit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head commit: %w", err)
+	}
+	repoLink := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(p.cfg.ServerURL, "/"), p.cfg.RepoOwner, p.cfg.RepoName)
+	ret := &AnnotatedInfo{
+		ChangedFiles: changedFiles,
+		Title:        strings.SplitN(head.Message, "\n", 2)[0],
+		LinkToChange: fmt.Sprintf("%s/commit/%s", repoLink, p.cfg.CommitSha),
+		PrCreator:    head.Author.Name,
+		AuthorIsBot:  strings.HasSuffix(head.Author.Name, "[bot]"),
+	}
+	if p.cfg.Author != "" {
+		// The git author is a name, while conditions on authors expect GitHub logins
+		ret.PrCreator = p.cfg.Author
+		ret.AuthorIsBot = p.cfg.AuthorIsBot || strings.HasSuffix(p.cfg.Author, "[bot]")
+		ret.LinkToAuthor = fmt.Sprintf("%s/%s", strings.TrimSuffix(p.cfg.ServerURL, "/"), p.cfg.Author)
+	}
+	if p.cfg.PullRequestNumber != 0 {
+		ret.LinkToChange = fmt.Sprintf("%s/pull/%d", repoLink, p.cfg.PullRequestNumber)
+		ret.PrBase = p.cfg.BaseBranch
+		ret.PrHead = p.cfg.HeadBranch
+	}
+	p.logger.Infof("changed files: %v", ret.ChangedFiles)
+	p.cache = ret
+	return ret, nil
+}
+
+// New returns the Fetch implementation for the source of the configuration
+func New(cfg config.Config, fromGh *PopulateFromGh, fromLocal *PopulateFromLocal) Fetch {
+	if cfg.Source == config.SourceLocal {
+		return fromLocal
+	}
+	return fromGh
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/annotatedinfo"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/gitattributes"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/notification"
 	"golang.org/x/sync/errgroup"
@@ -16,56 +18,79 @@ import (
 
 type Creator struct {
 	NotificationMerger   *notification.Merger
-	ghClient             *ghclient.GhClient
 	cfg                  config.Config
 	annotatedInfoFetcher annotatedinfo.Fetch
 	logger               logger.Logger
 	annotatedInfo        *annotatedinfo.AnnotatedInfo
+	gitAttributes        *gitattributes.Attributes
+	changedFiles         []ghclient.ChangedFile
 }
 
-func NewCreator(cfg config.Config, ghClient *ghclient.GhClient, annotatedInfo annotatedinfo.Fetch, notificationMerger *notification.Merger, logger logger.Logger) *Creator {
+func NewCreator(cfg config.Config, annotatedInfo annotatedinfo.Fetch, notificationMerger *notification.Merger, logger logger.Logger) *Creator {
 	return &Creator{
 		NotificationMerger:   notificationMerger,
-		ghClient:             ghClient,
 		cfg:                  cfg,
 		logger:               logger,
 		annotatedInfoFetcher: annotatedInfo,
 	}
 }
 
-func (c *Creator) CreateChanges(ctx context.Context, changedFiles []string) ([]ChangeToSend, error) {
+func (c *Creator) CreateChanges(ctx context.Context, changedFiles []ghclient.ChangedFile) ([]ChangeToSend, error) {
 	ai, err := c.annotatedInfoFetcher.Populate(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to populate annotated info: %w", err)
 	}
 	c.annotatedInfo = ai
+	changedFiles, err = c.removeIgnoredFiles(ctx, changedFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove ignored files: %w", err)
+	}
+	gitAttributesContent, err := c.NotificationMerger.NotificationLoader.GetContents(ctx, ".gitattributes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get .gitattributes: %w", err)
+	}
+	c.gitAttributes = gitattributes.Parse(gitAttributesContent)
+	c.changedFiles = changedFiles
+	// Load the notification files of every directory once, instead of once per changed file under it
+	paths := make([]string, 0, len(changedFiles))
+	for _, file := range changedFiles {
+		paths = append(paths, file.Filename)
+		if file.PreviousFilename != "" {
+			paths = append(paths, file.PreviousFilename)
+		}
+	}
+	if err := c.NotificationMerger.Preload(ctx, paths); err != nil {
+		return nil, fmt.Errorf("failed to load notification files: %w", err)
+	}
 	// For each changed file, find the notification file
 	// Merge them together
-	// Create a changetosend.ChangeToSend for each notification
+	// Create a pendingChange for each notification
+	// Render the templates of the pending changes, once for all the files they share
 	// Return the list of changes
 	type changeByIndex struct {
-		change *ChangeToSend
-		index  int
+		changes []pendingChange
+		index   int
 	}
 	changesByIndex := make([]changeByIndex, 0, len(changedFiles))
 	changesByIndexMu := sync.Mutex{}
 	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(c.cfg.ConcurrencyLimit())
 	for idx, file := range changedFiles {
 		idx := idx
 		file := file
 		eg.Go(func() error {
-			change, err := c.CreateChangesForFile(egCtx, file)
+			changes, err := c.createChangesForFile(egCtx, file)
 			if err != nil {
 				return fmt.Errorf("failed to create change for file %s: %w", file, err)
 			}
-			if change == nil {
+			if len(changes) == 0 {
 				return nil
 			}
 			changesByIndexMu.Lock()
 			defer changesByIndexMu.Unlock()
 			changesByIndex = append(changesByIndex, changeByIndex{
-				change: change,
-				index:  idx,
+				changes: changes,
+				index:   idx,
 			})
 			return nil
 		})
@@ -76,48 +101,268 @@ func (c *Creator) CreateChanges(ctx context.Context, changedFiles []string) ([]C
 	sort.Slice(changesByIndex, func(i, j int) bool {
 		return changesByIndex[i].index < changesByIndex[j].index
 	})
-	ret := make([]ChangeToSend, 0, len(changesByIndex))
+	pending := make([]pendingChange, 0, len(changesByIndex))
 	for _, changeByIndex := range changesByIndex {
-		ret = append(ret, *changeByIndex.change)
+		pending = append(pending, changeByIndex.changes...)
+	}
+	ret, err := c.renderChanges(pending)
+	if err != nil {
+		return nil, err
 	}
 	return MergeCommon(ret), nil
 }
 
-func (c *Creator) CreateChangesForFile(ctx context.Context, file string) (*ChangeToSend, error) {
-	notif, err := c.NotificationMerger.Merge(ctx, file)
+// pendingChange is a change to send for one changed file, whose message template is not rendered yet
+type pendingChange struct {
+	change      ChangeToSend
+	destination *notification.File
+	file        ghclient.ChangedFile
+}
+
+// groupKey identifies the pending changes that are rendered together: the ones to the same channel and area, that
+// execute the same templates
+func (p pendingChange) groupKey(changeType config.ChangeType) string {
+	area := p.change.Areas[0]
+	return strings.Join([]string{p.change.Channel, area.Dir, area.PrettyName, p.destination.TemplateKey(changeType)}, "\x00")
+}
+
+// renderChanges merges the pending changes of each group, in the order the groups first appear, and executes their
+// message templates once with all the files of the group
+func (c *Creator) renderChanges(pending []pendingChange) ([]ChangeToSend, error) {
+	indexes := make(map[string]int, len(pending))
+	groups := make([][]pendingChange, 0, len(pending))
+	for _, p := range pending {
+		key := p.groupKey(c.cfg.ChangeType)
+		idx, exists := indexes[key]
+		if !exists {
+			idx = len(groups)
+			indexes[key] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], p)
+	}
+	ret := make([]ChangeToSend, 0, len(groups))
+	for _, group := range groups {
+		change := group[0].change
+		files := []ghclient.ChangedFile{group[0].file}
+		for _, p := range group[1:] {
+			change = change.merge(p.change)
+			if !containsFile(files, p.file) {
+				files = append(files, p.file)
+			}
+		}
+		destination := group[0].destination
+		notifMsg, err := destination.ProcessTemplate(c.cfg.ChangeType, c.templateContext(files))
+		if err != nil {
+			return nil, fmt.Errorf("failed to process template for notification %v: %w", destination, err)
+		}
+		if notifMsg == "" {
+			c.logger.Debugf("notification message is empty for %s", destination.ChangedFile)
+		}
+		change.Messages = []string{notifMsg}
+		change.Areas[0].Messages = []string{notifMsg}
+		ret = append(ret, change)
+	}
+	return ret, nil
+}
+
+func containsFile(files []ghclient.ChangedFile, file ghclient.ChangedFile) bool {
+	for _, f := range files {
+		if f.Filename == file.Filename {
+			return true
+		}
+	}
+	return false
+}
+
+// removeIgnoredFiles drops the files listed in the repository's .notifyignore file
+func (c *Creator) removeIgnoredFiles(ctx context.Context, changedFiles []ghclient.ChangedFile) ([]ghclient.ChangedFile, error) {
+	ignored, err := c.NotificationMerger.NotificationLoader.LoadIgnoreFile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ignore file: %w", err)
+	}
+	if ignored == nil {
+		return changedFiles, nil
+	}
+	ret := make([]ghclient.ChangedFile, 0, len(changedFiles))
+	for _, file := range changedFiles {
+		if ignored.MatchesPath(file.Filename) {
+			c.logger.Debugf("ignoring %s", file)
+			continue
+		}
+		ret = append(ret, file)
+	}
+	return ret, nil
+}
+
+// createChangesForFile creates the changes to send for a changed file. A renamed file notifies the owners of both its
+// previous and its new location.
+func (c *Creator) createChangesForFile(ctx context.Context, file ghclient.ChangedFile) ([]pendingChange, error) {
+	ret, err := c.createChangesForPath(ctx, file.Filename, file)
+	if err != nil {
+		return nil, err
+	}
+	if file.PreviousFilename != "" && file.PreviousFilename != file.Filename {
+		previous, err := c.createChangesForPath(ctx, file.PreviousFilename, file)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, previous...)
+	}
+	return ret, nil
+}
+
+// createChangesForPath creates the changes to send to the owners of path, which file was changed at or renamed from
+func (c *Creator) createChangesForPath(ctx context.Context, path string, file ghclient.ChangedFile) ([]pendingChange, error) {
+	notif, err := c.NotificationMerger.Merge(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to merge notifications for path %s: %w", file, err)
+		return nil, fmt.Errorf("failed to merge notifications for path %s: %w", path, err)
 	}
 	if notif == nil {
 		return nil, nil
 	}
-	notifMsg, err := notif.ProcessTemplate(c.cfg.ChangeType)
+	notif, err = notif.WithConditions(c.cfg.ChangeType, c.changeContext(ctx, file))
 	if err != nil {
-		return nil, fmt.Errorf("failed to process template for notification %v: %w", notif, err)
+		return nil, fmt.Errorf("failed to evaluate conditions for path %s: %w", path, err)
+	}
+	modifiedFiles := []string{file.Filename}
+	var generatedFiles []string
+	if c.gitAttributes.IsGenerated(path) || c.gitAttributes.IsVendored(path) {
+		switch notif.GeneratedFiles(c.cfg.ChangeType) {
+		case notification.GeneratedFilesSkip:
+			c.logger.Debugf("skipping generated file %s", path)
+			return nil, nil
+		case notification.GeneratedFilesCollapse:
+			modifiedFiles, generatedFiles = nil, modifiedFiles
+		}
+	}
+	destinations := notif.Destinations(c.cfg.ChangeType)
+	ret := make([]pendingChange, 0, len(destinations))
+	for _, destination := range destinations {
+		change := c.createChange(destination, modifiedFiles, generatedFiles)
+		if change.Channel == "" {
+			continue
+		}
+		if matchedLines := destination.AllMatchedLines(c.cfg.ChangeType); len(matchedLines) > 0 {
+			change.MatchedLines = map[string][]string{file.Filename: matchedLines}
+		}
+		change.FileChanges = map[string]FileChange{
+			file.Filename: {
+				Status:           file.Status,
+				PreviousFilename: file.PreviousFilename,
+				Additions:        file.Additions,
+				Deletions:        file.Deletions,
+			},
+		}
+		if configChange, isConfigFile := c.configChange(file); isConfigFile {
+			change.ConfigChanges = append(change.ConfigChanges, configChange)
+		}
+		ret = append(ret, pendingChange{change: change, destination: destination, file: file})
+	}
+	return ret, nil
+}
+
+// beforeAndAfterSha returns the commits to compare to find what the pull request or commit changed
+func (c *Creator) beforeAndAfterSha() (string, string) {
+	if c.cfg.ChangeType.IsPullRequest() {
+		return c.cfg.BaseSha, c.cfg.HeadSha
+	}
+	return c.cfg.BeforeSha, c.cfg.CommitSha
+}
+
+// configChange links to the versions of file before and after the change, if it is a notification configuration file.
+// Notification files may be read at the base of a pull request, so reviewers see what the pull request changes.
+func (c *Creator) configChange(file ghclient.ChangedFile) (ConfigChange, bool) {
+	if !notification.IsConfigFile(file.Filename) && (file.PreviousFilename == "" || !notification.IsConfigFile(file.PreviousFilename)) {
+		return ConfigChange{}, false
+	}
+	beforeSha, afterSha := c.beforeAndAfterSha()
+	blobLink := func(sha string, path string) string {
+		if sha == "" || strings.Trim(sha, "0") == "" || c.cfg.ServerURL == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s/%s/%s/blob/%s/%s", strings.TrimSuffix(c.cfg.ServerURL, "/"), c.cfg.RepoOwner, c.cfg.RepoName, sha, path)
+	}
+	ret := ConfigChange{Path: file.Filename}
+	if file.Status != "added" {
+		previous := file.Filename
+		if file.PreviousFilename != "" {
+			previous = file.PreviousFilename
+		}
+		ret.BeforeLink = blobLink(beforeSha, previous)
 	}
-	if notifMsg == "" {
-		c.logger.Debugf("notification message is empty for %s", file)
+	if file.Status != "removed" {
+		ret.AfterLink = blobLink(afterSha, file.Filename)
 	}
+	return ret, true
+}
+
+// changeContext is what the when blocks of notifications are evaluated against
+func (c *Creator) changeContext(ctx context.Context, file ghclient.ChangedFile) notification.ChangeContext {
+	ret := notification.ChangeContext{
+		BaseBranch:  c.annotatedInfo.PrBase,
+		HeadBranch:  c.annotatedInfo.PrHead,
+		Author:      c.annotatedInfo.PrCreator,
+		AuthorIsBot: c.annotatedInfo.AuthorIsBot,
+		FileStatus:  file.Status,
+		Patch:       file.Patch,
+		AllFiles:    c.changedFiles,
+		CountFiles: func(dir string) (int, error) {
+			return c.NotificationMerger.NotificationLoader.CountFiles(ctx, dir)
+		},
+	}
+	if !c.cfg.ChangeType.IsPullRequest() {
+		ret.BaseBranch = c.cfg.RefName
+		ret.HeadBranch = c.cfg.RefName
+	}
+	return ret
+}
+
+// templateContext is what message templates are executed with, for the changed files they apply to
+func (c *Creator) templateContext(files []ghclient.ChangedFile) notification.TemplateContext {
+	ret := notification.TemplateContext{
+		Title:      c.annotatedInfo.Title,
+		Author:     c.annotatedInfo.PrCreator,
+		AuthorLink: c.annotatedInfo.LinkToAuthor,
+		Link:       c.annotatedInfo.LinkToChange,
+		BaseBranch: c.annotatedInfo.PrBase,
+		HeadBranch: c.annotatedInfo.PrHead,
+		CommitSha:  c.cfg.CommitSha,
+		Event:      c.cfg.ChangeType.String(),
+	}
+	if c.cfg.ChangeType.IsPullRequest() {
+		ret.PullRequestNumber = c.cfg.PullRequestNumber
+	} else {
+		ret.BaseBranch = c.cfg.RefName
+	}
+	return ret.WithFiles(files)
+}
+
+// createChange creates the change to send to the channel of destination, without its message
+func (c *Creator) createChange(destination *notification.File, modifiedFiles []string, generatedFiles []string) ChangeToSend {
 	change := ChangeToSend{
-		ModifiedFiles: []string{file},
-		Messages:      []string{notifMsg},
-		CommitSha:     c.cfg.CommitSha,
-		Creator:       c.annotatedInfo.PrCreator,
-		Branch:        c.annotatedInfo.PrBase,
-		LinkToChange:  c.annotatedInfo.LinkToChange,
-		LinkToAuthor:  c.annotatedInfo.LinkToAuthor,
-	}
-	change.Users = notif.AllUsers(c.cfg.ChangeType)
-	change.Groups = notif.AllGroups(c.cfg.ChangeType)
-	change.Channel = notif.Channel(c.cfg.ChangeType)
+		ModifiedFiles:  modifiedFiles,
+		GeneratedFiles: generatedFiles,
+		CommitSha:      c.cfg.CommitSha,
+		Creator:        c.annotatedInfo.PrCreator,
+		Branch:         c.annotatedInfo.PrBase,
+		LinkToChange:   c.annotatedInfo.LinkToChange,
+		LinkToAuthor:   c.annotatedInfo.LinkToAuthor,
+		Areas: []Area{{
+			PrettyName: strings.Join(destination.PrettyName, " / "),
+			Dir:        destination.Dir(),
+			Files:      modifiedFiles,
+		}},
+	}
+	change.Users = destination.AllUsers(c.cfg.ChangeType)
+	change.Groups = destination.AllGroups(c.cfg.ChangeType)
+	change.Channel = destination.Channel(c.cfg.ChangeType)
+	change.BlocksTemplate = destination.FindBlocksTemplate()
 	if c.cfg.RefName != "" {
 		change.Branch = c.cfg.RefName
 	}
-	if c.cfg.ChangeType == config.ChangeTypePullRequest {
+	if c.cfg.ChangeType.IsPullRequest() {
 		change.PullRequestNumber = c.cfg.PullRequestNumber
 	}
-	if change.Channel == "" {
-		return nil, nil
-	}
-	return &change, nil
+	return change
 }
@@ -0,0 +1,126 @@
+package changetosend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/annotatedinfo"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCreator returns a creator that reads the notification files of the repository checked out in
+// cfg.Workspace, or in a new directory with files if it is not set
+func newTestCreator(t *testing.T, cfg config.Config, files map[string]string) *Creator {
+	if cfg.Workspace == "" {
+		cfg.Workspace = t.TempDir()
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(cfg.Workspace, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Workspace, name), []byte(content), 0o600))
+	}
+	cfg.Source = config.SourceLocal
+	log := logger.NewTestLogger(t)
+	loader := notification.NewLoader(cfg, &ghclient.GhClient{}, localrepo.New(cfg, log), log)
+	return NewCreator(cfg, &annotatedinfo.AnnotatedInfo{PrCreator: "someone"}, notification.NewMerger(cfg, loader), log)
+}
+
+func TestCreateChangesIgnoredFiles(t *testing.T) {
+	creator := newTestCreator(t, config.Config{}, map[string]string{
+		".notifyignore":                    "*.lock\n",
+		"db/.action-notify-on-change.yaml": "ignore: ['docs/**']\npullRequest:\n  channel: '#db'\n",
+	})
+	changes, err := creator.CreateChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: "db/yarn.lock", Status: "modified"},
+		{Filename: "db/docs/schema.md", Status: "modified"},
+		{Filename: "db/schema.sql", Status: "modified"},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "#db", changes[0].Channel)
+	assert.Equal(t, []string{"db/schema.sql"}, changes[0].ModifiedFiles)
+
+	changes, err = creator.CreateChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: "db/yarn.lock", Status: "modified"},
+		{Filename: "db/docs/schema.md", Status: "modified"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestCreateChangesRename(t *testing.T) {
+	creator := newTestCreator(t, config.Config{}, map[string]string{
+		"old/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#old'\n  when:\n    statuses: [renamed, removed]\n",
+		"new/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#new'\n",
+	})
+	changes, err := creator.CreateChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: "new/name.go", PreviousFilename: "old/name.go", Status: "renamed"},
+		{Filename: "old/other.go", Status: "modified"},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Channel < changes[j].Channel
+	})
+	assert.Equal(t, "#new", changes[0].Channel)
+	assert.Equal(t, "#old", changes[1].Channel)
+	for _, change := range changes {
+		assert.Equal(t, []string{"new/name.go"}, change.ModifiedFiles)
+		assert.Equal(t, FileChange{Status: "renamed", PreviousFilename: "old/name.go"}, change.FileChanges["new/name.go"])
+	}
+}
+
+func TestCreateChangesConfigChanges(t *testing.T) {
+	creator := newTestCreator(t, config.Config{
+		ServerURL:         "https://github.com",
+		RepoOwner:         "cresta",
+		RepoName:          "repo",
+		PullRequestNumber: 1,
+		BaseSha:           "abc123",
+		HeadSha:           "def456",
+	}, map[string]string{
+		".action-notify-on-change.yaml": "pullRequest:\n  channel: '#all'\n",
+	})
+	changes, err := creator.CreateChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: ".action-notify-on-change.yaml", Status: "modified"},
+		{Filename: "main.go", Status: "modified"},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, []ConfigChange{{
+		Path:       ".action-notify-on-change.yaml",
+		BeforeLink: "https://github.com/cresta/repo/blob/abc123/.action-notify-on-change.yaml",
+		AfterLink:  "https://github.com/cresta/repo/blob/def456/.action-notify-on-change.yaml",
+	}}, changes[0].ConfigChanges)
+}
+
+func TestCreateChangesTemplateFiles(t *testing.T) {
+	creator := newTestCreator(t, config.Config{}, map[string]string{
+		"db/.action-notify-on-change.yaml": `pullRequest:
+  channel: '#db'
+messageTemplate: '{{ pluralize (len .Files) "file" "files" }} +{{ .Additions }}'
+rules:
+  - paths: ['*.sql']
+    messageTemplate: 'sql {{ len .Files }}'
+`,
+	})
+	changes, err := creator.CreateChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: "db/a.sql", Status: "modified", Additions: 3},
+		{Filename: "db/main.go", Status: "modified", Additions: 1},
+		{Filename: "db/b.sql", Status: "modified", Additions: 4},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"2 files +7 - sql 2", "1 file +1"}, changes[0].Messages)
+	require.Len(t, changes[0].Areas, 1)
+	assert.Equal(t, []string{"db/a.sql", "db/b.sql", "db/main.go"}, changes[0].Areas[0].Files)
+	assert.Equal(t, []string{"2 files +7 - sql 2", "1 file +1"}, changes[0].Areas[0].Messages)
+}
@@ -2,6 +2,7 @@ package changetosend
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -10,22 +11,103 @@ import (
 )
 
 type ChangeToSend struct {
-	Channel           string    // Which Slack channel to send the notification to
-	Users             []string  // Users to tag in the notification
-	Groups            []string  // Groups to tag in the notification
-	ModifiedFiles     []string  // Files that were modified
-	PullRequestNumber int       // Only set if this is a pull request
-	Branch            string    // Only set if this is a commit in a branch
-	CommitSha         string    // Only set if this is not a pull request, but a commit
-	Creator           string    // The user that created the pull request or commit
-	Timestamp         time.Time // The time the pull request or commit was created
-	LinkToChange      string    // Link to the pull request or commit
-	LinkToAuthor      string    // Link to the user that created the pull request or commit
-	Messages          []string  // The message to send (Extra part of the Slack notification)
+	Channel           string                // Which Slack channel to send the notification to
+	Users             []string              // Users to tag in the notification
+	Groups            []string              // Groups to tag in the notification
+	ModifiedFiles     []string              // Files that were modified
+	FileChanges       map[string]FileChange // How each of the modified files changed
+	MatchedLines      map[string][]string   // Lines of each modified file that matched a contentMatch regex
+	GeneratedFiles    []string              // Generated or vendored files that were modified, only shown as a count
+	PullRequestNumber int                   // Only set if this is a pull request
+	Branch            string                // Only set if this is a commit in a branch
+	CommitSha         string                // Only set if this is not a pull request, but a commit
+	Creator           string                // The user that created the pull request or commit
+	Timestamp         time.Time             // The time the pull request or commit was created
+	LinkToChange      string                // Link to the pull request or commit
+	LinkToAuthor      string                // Link to the user that created the pull request or commit
+	Messages          []string              // The message to send (Extra part of the Slack notification)
+	BlocksTemplate    string                // Block Kit template that replaces the default layout, if set
+	Areas             []Area                // One per notification file, or rule, that matched the modified files
+	ConfigChanges     []ConfigChange        // Notification configuration files changed by the pull request or commit
+}
+
+// ConfigChange links to both versions of a changed notification configuration file
+type ConfigChange struct {
+	Path       string // Path of the file after the change
+	BeforeLink string // Link to the file before the change, unless it was added
+	AfterLink  string // Link to the file after the change, unless it was removed
+}
+
+// Area is the part of a change that one notification file, or one of its rules, applies to
+type Area struct {
+	PrettyName string   // Pretty name of the notification file, if it has one
+	Dir        string   // Directory of the notification file
+	Files      []string // Modified files in the area
+	Messages   []string // Output of the message templates of the area
+}
+
+// Title returns how the area is called in messages: its pretty name, or its directory
+func (a Area) Title() string {
+	switch {
+	case a.PrettyName != "":
+		return a.PrettyName
+	case a.Dir != "" && a.Dir != ".":
+		return a.Dir + "/"
+	}
+	return ""
+}
+
+func (a Area) merge(from Area) Area {
+	a.Files = stringhelper.Deduplicate(append(a.Files, from.Files...))
+	a.Messages = append(a.Messages, from.Messages...)
+	return a
+}
+
+// mergeAreas merges the areas with the same directory and pretty name, keeping the order they first appear in
+func mergeAreas(areas []Area) []Area {
+	type areaKey struct {
+		prettyName string
+		dir        string
+	}
+	indexes := make(map[areaKey]int, len(areas))
+	ret := make([]Area, 0, len(areas))
+	for _, area := range areas {
+		key := areaKey{prettyName: area.PrettyName, dir: area.Dir}
+		if idx, exists := indexes[key]; exists {
+			ret[idx] = ret[idx].merge(area)
+			continue
+		}
+		indexes[key] = len(ret)
+		ret = append(ret, area)
+	}
+	return ret
+}
+
+// FileChange is how a modified file changed
+type FileChange struct {
+	Status           string // One of added, removed, modified, renamed, copied, changed or unchanged
+	PreviousFilename string // Only set if the file was renamed
+	Additions        int    // Lines added to the file
+	Deletions        int    // Lines removed from the file
+}
+
+// describe returns the status and name of the file, for display
+func (f FileChange) describe(file string) string {
+	if f.PreviousFilename != "" {
+		file = fmt.Sprintf("%s -> %s", f.PreviousFilename, file)
+	}
+	if f.Additions > 0 || f.Deletions > 0 {
+		file = fmt.Sprintf("%s (+%d -%d)", file, f.Additions, f.Deletions)
+	}
+	if f.Status == "" {
+		return file
+	}
+	return fmt.Sprintf("%-8s %s", f.Status, file)
 }
 
 type Sender interface {
 	SendMessage(ctx context.Context, change ChangeToSend) error
+	SendSubscriptionChange(ctx context.Context, change SubscriptionChangeToSend) error
 }
 
 func SendMessagesInParallel(ctx context.Context, sender Sender, changes []ChangeToSend) error {
@@ -41,11 +123,47 @@ func SendMessagesInParallel(ctx context.Context, sender Sender, changes []Change
 
 func (s ChangeToSend) merge(from ChangeToSend) ChangeToSend {
 	s.ModifiedFiles = stringhelper.Deduplicate(append(s.ModifiedFiles, from.ModifiedFiles...))
+	fileChanges := make(map[string]FileChange, len(s.FileChanges)+len(from.FileChanges))
+	for file, fileChange := range s.FileChanges {
+		fileChanges[file] = fileChange
+	}
+	for file, fileChange := range from.FileChanges {
+		fileChanges[file] = fileChange
+	}
+	s.FileChanges = fileChanges
+	matchedLines := make(map[string][]string, len(s.MatchedLines)+len(from.MatchedLines))
+	for file, lines := range s.MatchedLines {
+		matchedLines[file] = lines
+	}
+	for file, lines := range from.MatchedLines {
+		matchedLines[file] = stringhelper.Deduplicate(append(append([]string{}, matchedLines[file]...), lines...))
+	}
+	s.MatchedLines = matchedLines
+	s.GeneratedFiles = stringhelper.Deduplicate(append(s.GeneratedFiles, from.GeneratedFiles...))
 	s.Users = stringhelper.Deduplicate(append(s.Users, from.Users...))
+	s.Groups = stringhelper.Deduplicate(append(s.Groups, from.Groups...))
 	s.Messages = append(s.Messages, from.Messages...)
+	for _, configChange := range from.ConfigChanges {
+		if !containsConfigChange(s.ConfigChanges, configChange.Path) {
+			s.ConfigChanges = append(s.ConfigChanges, configChange)
+		}
+	}
+	s.Areas = mergeAreas(append(append([]Area{}, s.Areas...), from.Areas...))
+	if s.BlocksTemplate == "" {
+		s.BlocksTemplate = from.BlocksTemplate
+	}
 	return s
 }
 
+func containsConfigChange(configChanges []ConfigChange, path string) bool {
+	for _, configChange := range configChanges {
+		if configChange.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
 func MergeCommon(changes []ChangeToSend) []ChangeToSend {
 	// If there are multiple changes to the same channel, merge them together
 	// For this, we can send a single notification to Slack, instead of multiple
@@ -0,0 +1,23 @@
+package changetosend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeCommonAreas(t *testing.T) {
+	changes := MergeCommon([]ChangeToSend{
+		{Channel: "#db", Areas: []Area{{PrettyName: "Database", Dir: "db", Files: []string{"db/a.sql"}, Messages: []string{"schema"}}}},
+		{Channel: "#db", Areas: []Area{{Dir: "api", Files: []string{"api/a.go"}}}},
+		{Channel: "#db", Areas: []Area{{PrettyName: "Database", Dir: "db", Files: []string{"db/b.sql"}}}},
+	})
+	assert.Len(t, changes, 1)
+	assert.Equal(t, []Area{
+		{PrettyName: "Database", Dir: "db", Files: []string{"db/a.sql", "db/b.sql"}, Messages: []string{"schema"}},
+		{Dir: "api", Files: []string{"api/a.go"}},
+	}, changes[0].Areas)
+	assert.Equal(t, "Database", changes[0].Areas[0].Title())
+	assert.Equal(t, "api/", changes[0].Areas[1].Title())
+	assert.Equal(t, "", Area{Dir: "."}.Title())
+}
@@ -3,12 +3,14 @@ package changetosend
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/stringhelper"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/notification"
 	"github.com/slack-go/slack/slackutilsx"
 
 	"github.com/slack-go/slack"
@@ -19,8 +21,8 @@ type SlackDestination struct {
 	logger logger.Logger
 }
 
-func NewSlackDestination(logger logger.Logger, cfg config.Config) (*SlackDestination, error) {
-	ret := slack.New(cfg.SlackToken)
+func NewSlackDestination(logger logger.Logger, cfg config.Config, transport *http.Transport) (*SlackDestination, error) {
+	ret := slack.New(cfg.SlackToken, slack.OptionHTTPClient(&http.Client{Transport: transport}))
 	at, err := ret.AuthTest()
 	if err != nil {
 		return nil, fmt.Errorf("failed to auth test: %w", err)
@@ -37,7 +39,7 @@ var _ Sender = (*SlackDestination)(nil)
 func (s *SlackDestination) SendMessage(ctx context.Context, change ChangeToSend) error {
 	s.logger.Infof("Sending slack message for change")
 	userMap := s.mapOfUsersByEmail(ctx, change.Users)
-	channel, ts, text, err := s.client.SendMessageContext(ctx, change.Channel, createSlackMessage(change), slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl(), slack.MsgOptionText("Content change notification", false))
+	channel, ts, text, err := s.client.SendMessageContext(ctx, change.Channel, s.createMessage(change), slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl(), slack.MsgOptionText("Content change notification", false))
 	if err != nil {
 		return fmt.Errorf("failed to send message to channel %s: %w", change.Channel, err)
 	}
@@ -47,10 +49,63 @@ func (s *SlackDestination) SendMessage(ctx context.Context, change ChangeToSend)
 			return fmt.Errorf("failed to send message to channel %s: %w", change.Channel, err)
 		}
 	}
+	if len(change.MatchedLines) > 0 {
+		_, _, _, err = s.client.SendMessageContext(ctx, change.Channel, createMatchedLinesMessage(change), slack.MsgOptionTS(ts), slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl(), slack.MsgOptionText("Content change notification", false))
+		if err != nil {
+			return fmt.Errorf("failed to send message to channel %s: %w", change.Channel, err)
+		}
+	}
 	_, _, _ = channel, ts, text
 	return nil
 }
 
+// createMessage renders the blocks template of the change, falling back to the default layout if there is none or it
+// does not render to valid blocks
+func (s *SlackDestination) createMessage(change ChangeToSend) slack.MsgOption {
+	if change.BlocksTemplate == "" {
+		return createSlackMessage(change)
+	}
+	blocks, err := notification.RenderBlocks(change.BlocksTemplate, newBlocksTemplateContext(change))
+	if err != nil {
+		s.logger.Infof("failed to render blocks template for channel %s, using the default layout: %v", change.Channel, err)
+		return createSlackMessage(change)
+	}
+	return slack.MsgOptionBlocks(blocks...)
+}
+
+// BlocksTemplateContext is the data blocksTemplate is executed with
+type BlocksTemplateContext struct {
+	ChangeToSend
+	// Source describes the change, like "Pull request #12"
+	Source string
+	// Files are the modified files with their status and line counts, as shown by the default layout
+	Files []string
+	// Message is the output of the message templates, one per line
+	Message string
+}
+
+func newBlocksTemplateContext(change ChangeToSend) BlocksTemplateContext {
+	files := make([]string, 0, len(change.ModifiedFiles))
+	for _, file := range change.ModifiedFiles {
+		files = append(files, change.FileChanges[file].describe(file))
+	}
+	return BlocksTemplateContext{
+		ChangeToSend: change,
+		Source:       changeSourceText(change),
+		Files:        files,
+		Message:      strings.Join(stringhelper.RemoveEmptyAndDeDup(change.Messages), "\n"),
+	}
+}
+
+func (s *SlackDestination) SendSubscriptionChange(ctx context.Context, change SubscriptionChangeToSend) error {
+	s.logger.Infof("Sending slack subscription change message")
+	_, _, err := s.client.PostMessageContext(ctx, change.Channel, createSubscriptionChangeMessage(change), slack.MsgOptionDisableLinkUnfurl(), slack.MsgOptionDisableMediaUnfurl(), slack.MsgOptionText("Subscription change notification", false))
+	if err != nil {
+		return fmt.Errorf("failed to send subscription change to channel %s: %w", change.Channel, err)
+	}
+	return nil
+}
+
 func (s *SlackDestination) mapOfUsersByEmail(ctx context.Context, users []string) map[string]*slack.User {
 	ret := make(map[string]*slack.User)
 	for _, user := range users {
@@ -100,12 +155,95 @@ func createUsersMessage(change ChangeToSend, userMap map[string]*slack.User) sla
 	return slack.MsgOptionBlocks(blocks...)
 }
 
-func createSlackMessage(change ChangeToSend) slack.MsgOption {
+// maxMatchedLinesPerFile keeps matched lines messages under the size limit of Slack sections
+const maxMatchedLinesPerFile = 10
+
+func createMatchedLinesMessage(change ChangeToSend) slack.MsgOption {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", "*Matching lines:*", false, false), nil, nil),
+	}
+	for _, file := range change.ModifiedFiles {
+		lines, exists := change.MatchedLines[file]
+		if !exists {
+			continue
+		}
+		quoted := make([]string, 0, len(lines))
+		for idx, line := range lines {
+			if idx == maxMatchedLinesPerFile {
+				quoted = append(quoted, fmt.Sprintf("_and %d more_", len(lines)-idx))
+				break
+			}
+			quoted = append(quoted, "> "+slackutilsx.EscapeMessage(line))
+		}
+		text := fmt.Sprintf("`%s`\n%s", slackutilsx.EscapeMessage(file), strings.Join(quoted, "\n"))
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", text, false, false), nil, nil))
+	}
+	return slack.MsgOptionBlocks(blocks...)
+}
+
+// maxAreas keeps messages under the limit of 50 blocks of Slack
+const maxAreas = 20
+
+// changeAreas returns the areas of the change, or a single one with all of its files and messages if it has none
+func changeAreas(change ChangeToSend) []Area {
+	if len(change.Areas) > 0 {
+		return change.Areas
+	}
+	return []Area{{Files: change.ModifiedFiles, Messages: change.Messages}}
+}
+
+// createAreaBlocks lists the modified files of the area, headed by its title, followed by its custom message
+func createAreaBlocks(change ChangeToSend, area Area) []slack.Block {
 	var blocks []slack.Block
-	// https://api.slack.com/reference/block-kit/composition-objects#text
-	blocks = append(blocks,
-		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "Content change notification", false, false)),
-	)
+	title, messageHeader := area.Title(), ""
+	if title == "" {
+		title, messageHeader = "Modified files", "*Custom Message:*"
+	}
+	if len(area.Files) > 0 {
+		header := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*%s:*", slackutilsx.EscapeMessage(title)), false, false)
+		modifiedFiles := make([]string, 0, len(area.Files))
+		for _, file := range area.Files {
+			modifiedFiles = append(modifiedFiles, change.FileChanges[file].describe(file))
+		}
+		monoTextBlock := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("\n```\n%s\n```\n", strings.Join(modifiedFiles, "\n")), false, false)
+		blocks = append(blocks, slack.NewSectionBlock(header, []*slack.TextBlockObject{monoTextBlock}, nil))
+	}
+	msgToSend := strings.Join(stringhelper.RemoveEmptyAndDeDup(area.Messages), "\n")
+	if msgToSend == "" {
+		return blocks
+	}
+	var header *slack.TextBlockObject
+	if messageHeader != "" {
+		header = slack.NewTextBlockObject("mrkdwn", messageHeader, false, false)
+	}
+	return append(blocks, slack.NewSectionBlock(header, []*slack.TextBlockObject{
+		slack.NewTextBlockObject("mrkdwn", msgToSend, false, false),
+	}, nil))
+}
+
+// createConfigChangesBlock warns that the change edits who is notified, with links to both versions of each file
+func createConfigChangesBlock(configChanges []ConfigChange) slack.Block {
+	lines := make([]string, 0, len(configChanges)+1)
+	lines = append(lines, "*Notification configuration changed:*")
+	for _, configChange := range configChanges {
+		var versions []string
+		if configChange.BeforeLink != "" {
+			versions = append(versions, fmt.Sprintf("<%s|before>", configChange.BeforeLink))
+		}
+		if configChange.AfterLink != "" {
+			versions = append(versions, fmt.Sprintf("<%s|after>", configChange.AfterLink))
+		}
+		line := fmt.Sprintf("`%s`", slackutilsx.EscapeMessage(configChange.Path))
+		if len(versions) > 0 {
+			line += " (" + strings.Join(versions, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", strings.Join(lines, "\n"), false, false), nil, nil)
+}
+
+// createSourceBlock links to the pull request or commit, and to its author
+func createSourceBlock(change ChangeToSend) slack.Block {
 	sourceText := changeSourceText(change)
 	var sourceTextBlock *slack.TextBlockObject
 	if sourceText != "" {
@@ -126,19 +264,67 @@ func createSlackMessage(change ChangeToSend) slack.MsgOption {
 			creatorTextBlock = slack.NewTextBlockObject("plain_text", fmt.Sprintf("Author: %s", change.Creator), false, false)
 		}
 	}
-	blocks = append(blocks, slack.NewSectionBlock(nil, []*slack.TextBlockObject{sourceTextBlock, creatorTextBlock}, nil))
-	if len(change.ModifiedFiles) > 0 {
-		header := slack.NewTextBlockObject("mrkdwn", "*Modified files:*", false, false)
-		monoTextBlock := slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("\n```\n%s\n```\n", strings.Join(change.ModifiedFiles, "\n")), false, false)
-		blocks = append(blocks, slack.NewSectionBlock(header, []*slack.TextBlockObject{monoTextBlock}, nil))
+	return slack.NewSectionBlock(nil, []*slack.TextBlockObject{sourceTextBlock, creatorTextBlock}, nil)
+}
+
+func createSlackMessage(change ChangeToSend) slack.MsgOption {
+	var blocks []slack.Block
+	// https://api.slack.com/reference/block-kit/composition-objects#text
+	blocks = append(blocks,
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "Content change notification", false, false)),
+	)
+	blocks = append(blocks, createSourceBlock(change))
+	areas := changeAreas(change)
+	for idx, area := range areas {
+		if idx == maxAreas {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("+%d more areas", len(areas)-idx), false, false)))
+			break
+		}
+		blocks = append(blocks, createAreaBlocks(change, area)...)
 	}
-	msgToSend := strings.Join(stringhelper.RemoveEmptyAndDeDup(change.Messages), "\n")
-	if msgToSend != "" {
-		header := slack.NewTextBlockObject("mrkdwn", "*Custom Message:*", false, false)
-		blocks = append(blocks, slack.NewSectionBlock(
-			header, []*slack.TextBlockObject{
-				slack.NewTextBlockObject("mrkdwn", msgToSend, false, false),
-			}, nil))
+	if len(change.GeneratedFiles) > 0 {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("+%d generated files", len(change.GeneratedFiles)), false, false)))
+	}
+	if len(change.ConfigChanges) > 0 {
+		blocks = append(blocks, createConfigChangesBlock(change.ConfigChanges))
+	}
+	return slack.MsgOptionBlocks(blocks...)
+}
+
+func createSubscriptionChangeMessage(change SubscriptionChangeToSend) slack.MsgOption {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", "Subscription change notification", false, false)),
+		createSourceBlock(ChangeToSend{
+			PullRequestNumber: change.PullRequestNumber,
+			Branch:            change.Branch,
+			CommitSha:         change.CommitSha,
+			Creator:           change.Creator,
+			LinkToChange:      change.LinkToChange,
+			LinkToAuthor:      change.LinkToAuthor,
+		}),
+	}
+	for idx, subscriptionChange := range change.Changes {
+		if idx == maxAreas {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("+%d more notification files", len(change.Changes)-idx), false, false)))
+			break
+		}
+		lines := []string{fmt.Sprintf("`%s`", slackutilsx.EscapeMessage(subscriptionChange.Path))}
+		for _, diff := range []struct {
+			label  string
+			values []string
+		}{
+			{"Added channels", subscriptionChange.AddedChannels},
+			{"Removed channels", subscriptionChange.RemovedChannels},
+			{"Added users", subscriptionChange.AddedUsers},
+			{"Removed users", subscriptionChange.RemovedUsers},
+			{"Added groups", subscriptionChange.AddedGroups},
+			{"Removed groups", subscriptionChange.RemovedGroups},
+		} {
+			if len(diff.values) > 0 {
+				lines = append(lines, fmt.Sprintf("*%s:* %s", diff.label, slackutilsx.EscapeMessage(strings.Join(diff.values, ", "))))
+			}
+		}
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", strings.Join(lines, "\n"), false, false), nil, nil))
 	}
 	return slack.MsgOptionBlocks(blocks...)
 }
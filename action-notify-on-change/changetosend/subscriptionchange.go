@@ -0,0 +1,104 @@
+package changetosend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/notification"
+	"golang.org/x/sync/errgroup"
+)
+
+// SubscriptionChangeToSend tells a channel that a pull request or commit changes who notification files notify
+type SubscriptionChangeToSend struct {
+	Channel           string                            // Which Slack channel to send the notification to
+	Changes           []notification.SubscriptionChange // The notification files whose subscribers change
+	PullRequestNumber int                               // Only set if this is a pull request
+	Branch            string                            // Only set if this is a commit in a branch
+	CommitSha         string                            // Only set if this is not a pull request, but a commit
+	Creator           string                            // The user that created the pull request or commit
+	LinkToChange      string                            // Link to the pull request or commit
+	LinkToAuthor      string                            // Link to the user that created the pull request or commit
+}
+
+func SendSubscriptionChangesInParallel(ctx context.Context, sender Sender, changes []SubscriptionChangeToSend) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, change := range changes {
+		change := change
+		eg.Go(func() error {
+			return sender.SendSubscriptionChange(egCtx, change)
+		})
+	}
+	return eg.Wait()
+}
+
+// CreateSubscriptionChanges compares the versions of the notification files changed by the pull request or commit,
+// and returns one message per channel of either version that says who was added or removed
+func (c *Creator) CreateSubscriptionChanges(ctx context.Context, changedFiles []ghclient.ChangedFile) ([]SubscriptionChangeToSend, error) {
+	_, afterSha := c.beforeAndAfterSha()
+	if afterSha == "" || (c.cfg.ChangeType.IsPullRequest() && c.cfg.BaseSha == "") {
+		c.logger.Debugf("not comparing subscriptions, as the commits of the change are unknown")
+		return nil, nil
+	}
+	// Compare with where the pull request branched off, so changes made to the base branch since are not reported
+	beforeSha, err := c.NotificationMerger.NotificationLoader.BaseSha(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the commit to compare subscriptions with: %w", err)
+	}
+	var beforeLoader *notification.Loader
+	if beforeSha != "" {
+		beforeLoader = c.NotificationMerger.NotificationLoader.At(beforeSha)
+	}
+	afterLoader := c.NotificationMerger.NotificationLoader.At(afterSha)
+	byChannel := make(map[string][]notification.SubscriptionChange)
+	for _, file := range changedFiles {
+		previousFilename := file.Filename
+		if file.PreviousFilename != "" {
+			previousFilename = file.PreviousFilename
+		}
+		wasNotificationFile := notification.HasSubscriptions(previousFilename)
+		isNotificationFile := notification.HasSubscriptions(file.Filename)
+		if !wasNotificationFile && !isNotificationFile {
+			continue
+		}
+		var before, after notification.Subscriptions
+		if wasNotificationFile && file.Status != "added" && beforeLoader != nil {
+			if before, err = beforeLoader.LoadSubscriptions(ctx, previousFilename); err != nil {
+				return nil, fmt.Errorf("failed to load subscriptions of %s at %s: %w", previousFilename, beforeSha, err)
+			}
+		}
+		if isNotificationFile && file.Status != "removed" {
+			if after, err = afterLoader.LoadSubscriptions(ctx, file.Filename); err != nil {
+				return nil, fmt.Errorf("failed to load subscriptions of %s at %s: %w", file.Filename, afterSha, err)
+			}
+		}
+		change := notification.DiffSubscriptions(file.Filename, before, after)
+		if change.IsEmpty() {
+			continue
+		}
+		for _, channel := range change.Channels {
+			byChannel[channel] = append(byChannel[channel], change)
+		}
+	}
+	ret := make([]SubscriptionChangeToSend, 0, len(byChannel))
+	for channel, changes := range byChannel {
+		change := SubscriptionChangeToSend{
+			Channel:      channel,
+			Changes:      changes,
+			CommitSha:    c.cfg.CommitSha,
+			Creator:      c.annotatedInfo.PrCreator,
+			Branch:       c.cfg.RefName,
+			LinkToChange: c.annotatedInfo.LinkToChange,
+			LinkToAuthor: c.annotatedInfo.LinkToAuthor,
+		}
+		if c.cfg.ChangeType.IsPullRequest() {
+			change.PullRequestNumber = c.cfg.PullRequestNumber
+		}
+		ret = append(ret, change)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Channel < ret[j].Channel
+	})
+	return ret, nil
+}
@@ -0,0 +1,101 @@
+package changetosend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/annotatedinfo"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo/localrepotest"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/notification"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSubscriptionChanges(t *testing.T) {
+	repo := localrepotest.New(t)
+	base := repo.Commit(map[string]string{
+		"db/.action-notify-on-change.yaml":   "pullRequest:\n  channel: '#db'\n  users: [a@example.com, b@example.com]\n",
+		"old/.action-notify-on-change.yaml":  "pullRequest:\n  channel: '#old'\n  users: [old@example.com]\n",
+		"gone/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#gone'\n  users: [gone@example.com]\n",
+		".github/notify-on-change.yaml":      "routes:\n  - paths: ['**/*.sql']\n    pullRequest:\n      channel: '#sql'\n      users: [sql@example.com]\n",
+	})
+	head := repo.Commit(map[string]string{
+		"db/.action-notify-on-change.yaml":    "pullRequest:\n  channel: '#db'\n  users: [a@example.com, c@example.com]\n",
+		"new/.action-notify-on-change.yaml":   "pullRequest:\n  channel: '#db'\n  users: [new@example.com]\n",
+		"moved/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#old'\n  users: [old@example.com, moved@example.com]\n",
+		".github/notify-on-change.yaml":       "routes:\n  - paths: ['**/*.sql']\n    pullRequest:\n      channel: '#sql'\n      users: [sql@example.com, dba@example.com]\n",
+	}, "old/.action-notify-on-change.yaml", "gone/.action-notify-on-change.yaml")
+	// The base branch changes a notification file after the pull request branched from it
+	require.NoError(t, repo.Worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(base), Branch: plumbing.NewBranchReferenceName("main"), Create: true}))
+	movedBase := repo.Commit(map[string]string{
+		"db/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#db'\n  users: [a@example.com, b@example.com, d@example.com]\n",
+	})
+	changedFiles := []ghclient.ChangedFile{
+		{Filename: "db/.action-notify-on-change.yaml", Status: "modified"},
+		{Filename: "new/.action-notify-on-change.yaml", Status: "added"},
+		{Filename: "moved/.action-notify-on-change.yaml", PreviousFilename: "old/.action-notify-on-change.yaml", Status: "renamed"},
+		{Filename: "gone/.action-notify-on-change.yaml", Status: "removed"},
+		{Filename: ".github/notify-on-change.yaml", Status: "modified"},
+		{Filename: "db/schema.sql", Status: "modified"},
+	}
+	create := func(cfg config.Config) []SubscriptionChangeToSend {
+		cfg.Workspace = repo.Dir
+		creator := newTestCreator(t, cfg, nil)
+		creator.annotatedInfo = &annotatedinfo.AnnotatedInfo{PrCreator: "someone"}
+		changes, err := creator.CreateSubscriptionChanges(context.Background(), changedFiles)
+		require.NoError(t, err)
+		return changes
+	}
+
+	expected := []SubscriptionChangeToSend{
+		{Channel: "#db", Changes: []notification.SubscriptionChange{
+			{Path: "db/.action-notify-on-change.yaml", AddedUsers: []string{"c@example.com"}, RemovedUsers: []string{"b@example.com"}, Channels: []string{"#db"}},
+			{Path: "new/.action-notify-on-change.yaml", AddedChannels: []string{"#db"}, AddedUsers: []string{"new@example.com"}, Channels: []string{"#db"}},
+		}},
+		{Channel: "#gone", Changes: []notification.SubscriptionChange{
+			{Path: "gone/.action-notify-on-change.yaml", RemovedChannels: []string{"#gone"}, RemovedUsers: []string{"gone@example.com"}, Channels: []string{"#gone"}},
+		}},
+		{Channel: "#old", Changes: []notification.SubscriptionChange{
+			{Path: "moved/.action-notify-on-change.yaml", AddedUsers: []string{"moved@example.com"}, Channels: []string{"#old"}},
+		}},
+		{Channel: "#sql", Changes: []notification.SubscriptionChange{
+			{Path: ".github/notify-on-change.yaml", AddedUsers: []string{"dba@example.com"}, Channels: []string{"#sql"}},
+		}},
+	}
+	run := func(cfg config.Config) func(t *testing.T) {
+		return func(t *testing.T) {
+			changes := create(cfg)
+			require.Len(t, changes, len(expected))
+			for idx := range expected {
+				assert.Equal(t, expected[idx].Channel, changes[idx].Channel)
+				assert.Equal(t, expected[idx].Changes, changes[idx].Changes)
+				assert.Equal(t, cfg.PullRequestNumber, changes[idx].PullRequestNumber)
+			}
+		}
+	}
+	t.Run("pull request", run(config.Config{PullRequestNumber: 1, BaseSha: base, HeadSha: head, CommitSha: head}))
+	t.Run("base branch moved", run(config.Config{PullRequestNumber: 1, BaseSha: movedBase, HeadSha: head, CommitSha: head}))
+	t.Run("push", run(config.Config{ChangeType: config.ChangeTypeCommit, BeforeSha: base, CommitSha: head}))
+	t.Run("new branch", run(config.Config{ChangeType: config.ChangeTypeCommit, BeforeSha: "0000000000000000000000000000000000000000", CommitSha: head}))
+}
+
+func TestCreateSubscriptionChangesFirstCommit(t *testing.T) {
+	repo := localrepotest.New(t)
+	head := repo.Commit(map[string]string{
+		"db/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#db'\n  users: [a@example.com]\n",
+	})
+	creator := newTestCreator(t, config.Config{ChangeType: config.ChangeTypeCommit, BeforeSha: "0000000000000000000000000000000000000000", CommitSha: head, Workspace: repo.Dir}, nil)
+	creator.annotatedInfo = &annotatedinfo.AnnotatedInfo{PrCreator: "someone"}
+	changes, err := creator.CreateSubscriptionChanges(context.Background(), []ghclient.ChangedFile{
+		{Filename: "db/.action-notify-on-change.yaml", Status: "added"},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, []notification.SubscriptionChange{
+		{Path: "db/.action-notify-on-change.yaml", AddedChannels: []string{"#db"}, AddedUsers: []string{"a@example.com"}, Channels: []string{"#db"}},
+	}, changes[0].Changes)
+}
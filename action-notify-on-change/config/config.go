@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 type Config struct {
 	GithubToken       string
 	SlackToken        string
@@ -12,11 +14,144 @@ type Config struct {
 	RefName           string
 	PullRequestNumber int
 	ChangeType        ChangeType
+	// LoaderMode is how notification files are read from the repository
+	LoaderMode LoaderMode
+	// Source is where notification files and changed files are read from
+	Source Source
+	// Workspace is the directory the repository is checked out in
+	Workspace string
+	// ServerURL is the URL of the GitHub server, used to link to changes read from the workspace
+	ServerURL string
+	// APIURL and GraphqlURL are the endpoints of the REST and GraphQL APIs, which differ on GitHub Enterprise Server
+	APIURL     string
+	GraphqlURL string
+	// CABundle is the path of a PEM file of certificates trusted on top of the system ones
+	CABundle string
+	// HTTPProxy is the proxy requests are sent through. If empty, the proxy environment variables are used.
+	HTTPProxy string
+	// HeadBranch is the branch the pull request comes from
+	HeadBranch string
+	// BaseSha and HeadSha are the commits the pull request targets and comes from
+	BaseSha string
+	HeadSha string
+	// BeforeSha is the commit the branch pointed to before a push
+	BeforeSha string
+	// ConfigRef is which commit of a pull request notification files are read at
+	ConfigRef ConfigRef
+	// Concurrency is how many files are processed, and GitHub requests sent, at once
+	Concurrency int
+	// Author is the GitHub login of who opened the pull request or pushed the commit, read from the event
+	Author      string
+	AuthorIsBot bool
+}
+
+// DefaultConcurrency is the concurrency when none is configured
+const DefaultConcurrency = 10
+
+// ConcurrencyLimit returns Concurrency, or DefaultConcurrency if it is not set
+func (c Config) ConcurrencyLimit() int {
+	if c.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return c.Concurrency
+}
+
+type ConfigRef string
+
+const (
+	// ConfigRefHead reads notification files at the commit being notified about, so pull requests can change them
+	ConfigRefHead ConfigRef = "head"
+	// ConfigRefBase reads notification files of pull requests at the commit they target, so a pull request cannot
+	// change who is notified about it
+	ConfigRefBase ConfigRef = "base"
+)
+
+// ConfigSha returns the commit notification files are read at
+func (c Config) ConfigSha() string {
+	if c.ConfigRef == ConfigRefBase && c.PullRequestNumber != 0 && c.BaseSha != "" {
+		return c.BaseSha
+	}
+	return c.CommitSha
 }
 
+type Source string
+
+const (
+	// SourceGithub reads notification files and changed files with the GitHub API
+	SourceGithub Source = "github"
+	// SourceLocal reads notification files and changed files from the repository checked out in Workspace
+	SourceLocal Source = "local"
+)
+
+type LoaderMode string
+
+const (
+	// LoaderModeContents reads each notification file, or finds it missing, with one request per directory
+	LoaderModeContents LoaderMode = "contents"
+	// LoaderModeTree lists the files of the repository once and reads every notification file in a few batched
+	// GraphQL queries
+	LoaderModeTree LoaderMode = "tree"
+)
+
 type ChangeType int
 
 const (
+	// ChangeTypePullRequest is a pull request event without a more specific change type below
 	ChangeTypePullRequest ChangeType = iota
 	ChangeTypeCommit
+	ChangeTypePullRequestOpened
+	ChangeTypePullRequestReadyForReview
+	ChangeTypePullRequestSynchronize
+	ChangeTypePullRequestMerged
+	ChangeTypePullRequestClosed
+	ChangeTypePullRequestReopened
+	ChangeTypePullRequestReviewRequested
 )
+
+// changeTypeNames are the names of change types, which are also the keys of their sections in notification files
+var changeTypeNames = map[ChangeType]string{
+	ChangeTypePullRequest:                "pullRequest",
+	ChangeTypeCommit:                     "commit",
+	ChangeTypePullRequestOpened:          "opened",
+	ChangeTypePullRequestReadyForReview:  "readyForReview",
+	ChangeTypePullRequestSynchronize:     "synchronize",
+	ChangeTypePullRequestMerged:          "merged",
+	ChangeTypePullRequestClosed:          "closed",
+	ChangeTypePullRequestReopened:        "reopened",
+	ChangeTypePullRequestReviewRequested: "reviewRequested",
+}
+
+func (c ChangeType) String() string {
+	if name, exists := changeTypeNames[c]; exists {
+		return name
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(c))
+}
+
+// IsPullRequest returns true for every pull request change type, whatever the action of the event
+func (c ChangeType) IsPullRequest() bool {
+	return c != ChangeTypeCommit
+}
+
+// pullRequestChangeTypes maps the action of a pull_request webhook payload to its change type
+var pullRequestChangeTypes = map[string]ChangeType{
+	"opened":           ChangeTypePullRequestOpened,
+	"ready_for_review": ChangeTypePullRequestReadyForReview,
+	"synchronize":      ChangeTypePullRequestSynchronize,
+	"closed":           ChangeTypePullRequestClosed,
+	"reopened":         ChangeTypePullRequestReopened,
+	"review_requested": ChangeTypePullRequestReviewRequested,
+}
+
+// PullRequestChangeType returns the change type of a pull_request event with the given action. A closed pull request
+// that was merged is ChangeTypePullRequestMerged.
+func PullRequestChangeType(action string, merged bool) ChangeType {
+	ct, exists := pullRequestChangeTypes[action]
+	if !exists {
+		return ChangeTypePullRequest
+	}
+	if ct == ChangeTypePullRequestClosed && merged {
+		return ChangeTypePullRequestMerged
+	}
+	return ct
+}
@@ -16,7 +16,10 @@ func NewFromGithubActions(action *githubactions.Action) (Config, error) {
 	ghOwner, ghName := ghCtx.Repo()
 	prNumber := 0
 	ct := ChangeTypeCommit
-	if ghCtx.EventName == "pull_request" {
+	var baseSha, headSha string
+	beforeSha, _ := ghCtx.Event["before"].(string)
+	switch ghCtx.EventName {
+	case "pull_request":
 		rgx := regexp.MustCompile(`^refs/pull/([0-9]+)/merge$`)
 		matches := rgx.FindStringSubmatch(ghCtx.Ref)
 		if len(matches) != 2 {
@@ -27,7 +30,59 @@ func NewFromGithubActions(action *githubactions.Action) (Config, error) {
 		if err != nil {
 			return Config{}, fmt.Errorf("failed to parse pull request number from ref %s: %w", ghCtx.Ref, err)
 		}
-		ct = ChangeTypePullRequest
+	case "pull_request_target":
+		// Runs on the base branch, so the ref does not name the pull request
+		number, ok := ghCtx.Event["number"].(float64)
+		if !ok {
+			return Config{}, fmt.Errorf("failed to find pull request number in %s event", ghCtx.EventName)
+		}
+		prNumber = int(number)
+	}
+	if prNumber != 0 {
+		action, _ := ghCtx.Event["action"].(string)
+		var merged bool
+		if pr, ok := ghCtx.Event["pull_request"].(map[string]any); ok {
+			merged, _ = pr["merged"].(bool)
+			if base, ok := pr["base"].(map[string]any); ok {
+				baseSha, _ = base["sha"].(string)
+			}
+			if head, ok := pr["head"].(map[string]any); ok {
+				headSha, _ = head["sha"].(string)
+			}
+		}
+		ct = PullRequestChangeType(action, merged)
+	}
+	author, authorIsBot := eventAuthor(ghCtx.Event)
+	loaderMode := LoaderMode(action.GetInput("loader"))
+	switch loaderMode {
+	case LoaderModeContents, LoaderModeTree:
+	case "":
+		loaderMode = LoaderModeContents
+	default:
+		return Config{}, fmt.Errorf("unknown loader %s, expected %s or %s", loaderMode, LoaderModeContents, LoaderModeTree)
+	}
+	source := Source(action.GetInput("source"))
+	switch source {
+	case SourceGithub, SourceLocal:
+	case "":
+		source = SourceGithub
+	default:
+		return Config{}, fmt.Errorf("unknown source %s, expected %s or %s", source, SourceGithub, SourceLocal)
+	}
+	configRef := ConfigRef(action.GetInput("config-ref"))
+	switch configRef {
+	case ConfigRefHead, ConfigRefBase:
+	case "":
+		configRef = ConfigRefHead
+	default:
+		return Config{}, fmt.Errorf("unknown config-ref %s, expected %s or %s", configRef, ConfigRefHead, ConfigRefBase)
+	}
+	concurrency := DefaultConcurrency
+	if input := action.GetInput("concurrency"); input != "" {
+		concurrency, err = strconv.Atoi(input)
+		if err != nil || concurrency <= 0 {
+			return Config{}, fmt.Errorf("invalid concurrency %s, expected a positive number", input)
+		}
 	}
 	return Config{
 		GithubToken:       action.GetInput("github-token"),
@@ -41,9 +96,47 @@ func NewFromGithubActions(action *githubactions.Action) (Config, error) {
 		RefName:           ghCtx.RefName,
 		PullRequestNumber: prNumber,
 		ChangeType:        ct,
+		LoaderMode:        loaderMode,
+		Source:            source,
+		Workspace:         ghCtx.Workspace,
+		ServerURL:         inputOr(action, "github-server-url", ghCtx.ServerURL),
+		APIURL:            inputOr(action, "github-api-url", ghCtx.APIURL),
+		GraphqlURL:        inputOr(action, "github-graphql-url", ghCtx.GraphqlURL),
+		CABundle:          action.GetInput("ca-bundle"),
+		HTTPProxy:         action.GetInput("http-proxy"),
+		HeadBranch:        ghCtx.HeadRef,
+		BaseSha:           baseSha,
+		HeadSha:           headSha,
+		BeforeSha:         beforeSha,
+		ConfigRef:         configRef,
+		Concurrency:       concurrency,
+		Author:            author,
+		AuthorIsBot:       authorIsBot,
 	}, nil
 }
 
+// eventAuthor returns the GitHub login of who opened the pull request of event, or else of who triggered it, and
+// whether they are a bot
+func eventAuthor(event map[string]any) (string, bool) {
+	user, _ := event["sender"].(map[string]any)
+	if pr, ok := event["pull_request"].(map[string]any); ok {
+		if prUser, ok := pr["user"].(map[string]any); ok {
+			user = prUser
+		}
+	}
+	login, _ := user["login"].(string)
+	userType, _ := user["type"].(string)
+	return login, userType == "Bot"
+}
+
+// inputOr returns the input called name, or def if it is not set
+func inputOr(action *githubactions.Action, name string, def string) string {
+	if input := action.GetInput(name); input != "" {
+		return input
+	}
+	return def
+}
+
 func NewGithubActionsFromEnv() *githubactions.Action {
 	return githubactions.New()
 }
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventAuthor(t *testing.T) {
+	run := func(event map[string]any, expectedLogin string, expectedIsBot bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			login, isBot := eventAuthor(event)
+			assert.Equal(t, expectedLogin, login)
+			assert.Equal(t, expectedIsBot, isBot)
+		}
+	}
+	t.Run("push", run(map[string]any{"sender": map[string]any{"login": "someone", "type": "User"}}, "someone", false))
+	t.Run("pull request", run(map[string]any{
+		"sender":       map[string]any{"login": "reviewer", "type": "User"},
+		"pull_request": map[string]any{"user": map[string]any{"login": "renovate[bot]", "type": "Bot"}},
+	}, "renovate[bot]", true))
+	t.Run("no sender", run(map[string]any{}, "", false))
+}
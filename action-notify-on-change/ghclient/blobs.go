@@ -0,0 +1,73 @@
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// blobsPerQuery keeps GraphQL queries well under the node limits of GitHub
+const blobsPerQuery = 50
+
+// GetBlobs returns the content of the files at paths at the commit, reading up to 50 files per GraphQL query. Files
+// that do not exist, are binary or are too big for GraphQL are missing from the result.
+func (g *GhClient) GetBlobs(ctx context.Context, paths []string) (map[string][]byte, error) {
+	ret := make(map[string][]byte, len(paths))
+	for start := 0; start < len(paths); start += blobsPerQuery {
+		end := start + blobsPerQuery
+		if end > len(paths) {
+			end = len(paths)
+		}
+		if err := g.getBlobs(ctx, paths[start:end], ret); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+type blobObject struct {
+	Blob struct {
+		Text        *string
+		IsBinary    bool
+		IsTruncated bool
+	} `graphql:"... on Blob"`
+}
+
+// getBlobs reads paths in a single query, with one aliased object field per path, into ret
+func (g *GhClient) getBlobs(ctx context.Context, paths []string, into map[string][]byte) error {
+	g.logger.Debugf("getting %d blobs at %s", len(paths), g.cfg.CommitSha)
+	variables := map[string]interface{}{
+		"owner": githubv4.String(g.cfg.RepoOwner),
+		"name":  githubv4.String(g.cfg.RepoName),
+	}
+	// githubv4 builds queries from struct tags, so aliases for a variable number of files need a struct built at runtime
+	fields := make([]reflect.StructField, 0, len(paths))
+	for idx, path := range paths {
+		variables[fmt.Sprintf("e%d", idx)] = githubv4.String(fmt.Sprintf("%s:%s", g.cfg.CommitSha, path))
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", idx),
+			Type: reflect.TypeOf(blobObject{}),
+			Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"f%d: object(expression: $e%d)"`, idx, idx)),
+		})
+	}
+	repository := reflect.StructOf(fields)
+	query := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Repository",
+		Type: repository,
+		Tag:  `graphql:"repository(owner: $owner, name: $name)"`,
+	}}))
+	if err := g.graphqlClient.Query(ctx, query.Interface(), variables); err != nil {
+		return fmt.Errorf("failed to query blobs at %s: %w", g.cfg.CommitSha, err)
+	}
+	result := query.Elem().Field(0)
+	for idx, path := range paths {
+		blob := result.Field(idx).Interface().(blobObject).Blob
+		if blob.Text == nil || blob.IsBinary || blob.IsTruncated {
+			continue
+		}
+		into[path] = []byte(*blob.Text)
+	}
+	return nil
+}
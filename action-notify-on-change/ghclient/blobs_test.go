@@ -0,0 +1,45 @@
+package ghclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBlobs(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = append(queries, body.Query)
+		assert.Equal(t, "abc:a/.action-notify-on-change.yaml", body.Variables["e0"])
+		_, _ = w.Write([]byte(`{"data": {"repository": {
+			"f0": {"text": "commit:\n  channel: \"#a\"\n", "isBinary": false},
+			"f1": null,
+			"f2": {"text": null, "isBinary": true}
+		}}}`))
+	}))
+	defer server.Close()
+	g := &GhClient{
+		graphqlClient: githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		cfg:           config.Config{RepoOwner: "cresta", RepoName: "repo", CommitSha: "abc"},
+		logger:        logger.NewTestLogger(t),
+	}
+	blobs, err := g.GetBlobs(context.Background(), []string{"a/.action-notify-on-change.yaml", "missing.yaml", "image.png"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"a/.action-notify-on-change.yaml": []byte("commit:\n  channel: \"#a\"\n")}, blobs)
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0], "f0: object(expression: $e0)")
+	assert.Contains(t, queries[0], "f2: object(expression: $e2)")
+}
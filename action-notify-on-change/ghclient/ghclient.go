@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
 
@@ -16,35 +18,102 @@ import (
 type GhClient struct {
 	restClient    *github.Client
 	graphqlClient *githubv4.Client
+	rateLimits    *rateLimitTransport
 	cfg           config.Config
 	logger        logger.Logger
+
+	treeOnce sync.Once
+	tree     []string
+	treeErr  error
 }
 
-func New(cfg config.Config, logger logger.Logger) (*GhClient, error) {
+func New(cfg config.Config, transport *http.Transport, logger logger.Logger) (*GhClient, error) {
 	// TODO: What is the right way to do this?
 	ctx := context.Background()
-	restClient, err := newGithubClient(ctx, cfg.GithubToken, logger)
+	rateLimits := newRateLimitTransport(transport, cfg.ConcurrencyLimit(), logger)
+	httpClient := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.GithubToken}),
+			Base:   rateLimits,
+		},
+	}
+	if cfg.Source == config.SourceLocal {
+		// The GitHub API is not needed to read the checkout, so do not require a working token
+		restClient, err := newRestClient(cfg, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return &GhClient{
+			restClient:    restClient,
+			graphqlClient: newGraphQLClient(cfg, httpClient),
+			rateLimits:    rateLimits,
+			cfg:           cfg,
+			logger:        logger,
+		}, nil
+	}
+	restClient, err := newGithubClient(ctx, cfg, httpClient, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github rest client: %w", err)
 	}
-	graphqlClient, err := newGithubGraphQLClient(ctx, cfg.GithubToken, logger)
+	graphqlClient, err := newGithubGraphQLClient(ctx, cfg, httpClient, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create github graphql client: %w", err)
 	}
 	return &GhClient{
 		restClient:    restClient,
 		graphqlClient: graphqlClient,
+		rateLimits:    rateLimits,
 		cfg:           cfg,
 		logger:        logger,
 	}, nil
 }
 
-func newGithubClient(ctx context.Context, token string, l logger.Logger) (*github.Client, error) {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+// At returns a client that reads the contents and files of the repository at sha, instead of at the commit being
+// notified about
+func (g *GhClient) At(sha string) *GhClient {
+	cfg := g.cfg
+	cfg.CommitSha = sha
+	return &GhClient{
+		restClient:    g.restClient,
+		graphqlClient: g.graphqlClient,
+		rateLimits:    g.rateLimits,
+		cfg:           cfg,
+		logger:        g.logger,
+	}
+}
+
+// LogRateLimits logs how much of its GitHub API quota the run left
+func (g *GhClient) LogRateLimits() {
+	if g.rateLimits != nil {
+		g.rateLimits.logQuotas()
+	}
+}
+
+// newRestClient returns a REST client for the API at cfg.APIURL, which is github.com if it is not set
+func newRestClient(cfg config.Config, httpClient *http.Client) (*github.Client, error) {
+	if cfg.APIURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+	client, err := github.NewEnterpriseClient(cfg.APIURL, cfg.APIURL, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create github rest client for %s: %w", cfg.APIURL, err)
+	}
+	return client, nil
+}
+
+// newGraphQLClient returns a GraphQL client for the API at cfg.GraphqlURL, which is github.com if it is not set
+func newGraphQLClient(cfg config.Config, httpClient *http.Client) *githubv4.Client {
+	if cfg.GraphqlURL == "" {
+		return githubv4.NewClient(httpClient)
+	}
+	return githubv4.NewEnterpriseClient(cfg.GraphqlURL, httpClient)
+}
+
+func newGithubClient(ctx context.Context, cfg config.Config, httpClient *http.Client, l logger.Logger) (*github.Client, error) {
+	client, err := newRestClient(cfg, httpClient)
+	if err != nil {
+		return nil, err
+	}
 	s, _, err := client.Zen(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query github zen: %w", err)
@@ -53,13 +122,8 @@ func newGithubClient(ctx context.Context, token string, l logger.Logger) (*githu
 	return client, nil
 }
 
-func newGithubGraphQLClient(ctx context.Context, token string, l logger.Logger) (*githubv4.Client, error) {
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	httpClient := oauth2.NewClient(ctx, src)
-
-	client := githubv4.NewClient(httpClient)
+func newGithubGraphQLClient(ctx context.Context, cfg config.Config, httpClient *http.Client, l logger.Logger) (*githubv4.Client, error) {
+	client := newGraphQLClient(cfg, httpClient)
 	// Test query to make sure the token works
 	var query struct {
 		Viewer struct {
@@ -102,12 +166,46 @@ func (g *GhClient) GetContents(ctx context.Context, filePath string) ([]byte, er
 	return []byte(fileContent), nil
 }
 
+// ChangedFile is a file changed by a pull request or commit
+type ChangedFile struct {
+	Filename string
+	// One of added, removed, modified, renamed, copied, changed or unchanged
+	Status string
+	// The name of the file before it was renamed
+	PreviousFilename string
+	// Unified diff of the file. GitHub leaves it empty for binary and very large files.
+	Patch     string
+	Additions int
+	Deletions int
+}
+
+func (c ChangedFile) String() string {
+	if c.PreviousFilename != "" {
+		return fmt.Sprintf("%s -> %s", c.PreviousFilename, c.Filename)
+	}
+	return c.Filename
+}
+
+func newChangedFile(file *github.CommitFile) ChangedFile {
+	return ChangedFile{
+		Filename:         file.GetFilename(),
+		Status:           file.GetStatus(),
+		PreviousFilename: file.GetPreviousFilename(),
+		Patch:            file.GetPatch(),
+		Additions:        file.GetAdditions(),
+		Deletions:        file.GetDeletions(),
+	}
+}
+
 type PrInfo struct {
+	PrTitle      string
 	PrLink       string
 	AuthorLink   string
 	PrCreator    string
 	PrBase       string
-	ChangedFiles []string
+	PrHead       string
+	AuthorIsBot  bool
+	ChangedFiles []ChangedFile
 }
 
 func (g *GhClient) PrInfo(ctx context.Context) (*PrInfo, error) {
@@ -122,6 +220,9 @@ func (g *GhClient) PrInfo(ctx context.Context) (*PrInfo, error) {
 		if ret.PrLink == "" {
 			ret.PrLink = prInfo.GetHTMLURL()
 		}
+		if ret.PrTitle == "" {
+			ret.PrTitle = prInfo.GetTitle()
+		}
 		if ret.AuthorLink == "" {
 			ret.AuthorLink = prInfo.User.GetHTMLURL()
 		}
@@ -131,12 +232,16 @@ func (g *GhClient) PrInfo(ctx context.Context) (*PrInfo, error) {
 		if ret.PrBase == "" {
 			ret.PrBase = prInfo.GetBase().GetRef()
 		}
+		if ret.PrHead == "" {
+			ret.PrHead = prInfo.GetHead().GetRef()
+		}
+		ret.AuthorIsBot = prInfo.GetUser().GetType() == "Bot"
 		files, resp, err := g.restClient.PullRequests.ListFiles(ctx, g.cfg.RepoOwner, g.cfg.RepoName, g.cfg.PullRequestNumber, &opts)
 		if err != nil || resp.StatusCode != http.StatusOK {
 			return nil, fmt.Errorf("failed to list pull request files: %s", resp.Status)
 		}
 		for _, file := range files {
-			ret.ChangedFiles = append(ret.ChangedFiles, file.GetFilename())
+			ret.ChangedFiles = append(ret.ChangedFiles, newChangedFile(file))
 		}
 		if resp.NextPage == 0 {
 			break
@@ -147,10 +252,13 @@ func (g *GhClient) PrInfo(ctx context.Context) (*PrInfo, error) {
 }
 
 type CommitInfo struct {
+	// First line of the commit message
+	Title        string
 	AuthorLink   string
 	LinkToChange string
 	AuthorName   string
-	ChangedFiles []string
+	AuthorIsBot  bool
+	ChangedFiles []ChangedFile
 }
 
 func (g *GhClient) GetCommit(ctx context.Context) (*CommitInfo, error) {
@@ -168,9 +276,13 @@ func (g *GhClient) GetCommit(ctx context.Context) (*CommitInfo, error) {
 		if ret.LinkToChange == "" {
 			ret.LinkToChange = commit.GetHTMLURL()
 		}
+		if ret.Title == "" {
+			ret.Title = strings.SplitN(commit.GetCommit().GetMessage(), "\n", 2)[0]
+		}
 		if ret.AuthorLink == "" {
 			ret.AuthorLink = commit.GetAuthor().GetHTMLURL()
 		}
+		ret.AuthorIsBot = commit.GetAuthor().GetType() == "Bot"
 		if err != nil {
 			return nil, fmt.Errorf("failed to get commit: %w", err)
 		}
@@ -178,7 +290,7 @@ func (g *GhClient) GetCommit(ctx context.Context) (*CommitInfo, error) {
 			return nil, fmt.Errorf("failed to get commit: %s", resp.Status)
 		}
 		for _, file := range commit.Files {
-			ret.ChangedFiles = append(ret.ChangedFiles, file.GetFilename())
+			ret.ChangedFiles = append(ret.ChangedFiles, newChangedFile(file))
 		}
 		if resp.NextPage == 0 {
 			break
@@ -187,3 +299,91 @@ func (g *GhClient) GetCommit(ctx context.Context) (*CommitInfo, error) {
 	}
 	return ret, nil
 }
+
+// BaseSha returns the commit the changes are compared with: where a pull request branched from its base, or what
+// the branch pointed to before a push. It returns "" for the first commit of a repository.
+func (g *GhClient) BaseSha(ctx context.Context) (string, error) {
+	if g.cfg.PullRequestNumber != 0 {
+		comparison, _, err := g.restClient.Repositories.CompareCommits(ctx, g.cfg.RepoOwner, g.cfg.RepoName, g.cfg.BaseSha, g.cfg.HeadSha, &github.ListOptions{PerPage: 1})
+		if err != nil {
+			return "", fmt.Errorf("failed to compare %s with %s: %w", g.cfg.BaseSha, g.cfg.HeadSha, err)
+		}
+		return comparison.GetMergeBaseCommit().GetSHA(), nil
+	}
+	if g.cfg.BeforeSha != "" && strings.Trim(g.cfg.BeforeSha, "0") != "" {
+		return g.cfg.BeforeSha, nil
+	}
+	// A new branch: compare with the parent of the pushed commit
+	commit, _, err := g.restClient.Git.GetCommit(ctx, g.cfg.RepoOwner, g.cfg.RepoName, g.cfg.CommitSha)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %w", g.cfg.CommitSha, err)
+	}
+	if len(commit.Parents) == 0 {
+		return "", nil
+	}
+	return commit.Parents[0].GetSHA(), nil
+}
+
+// ListFiles returns the path of every file in the repository at the commit. It is only fetched once.
+func (g *GhClient) ListFiles(ctx context.Context) ([]string, error) {
+	g.treeOnce.Do(func() {
+		g.logger.Debugf("listing files at %s", g.cfg.CommitSha)
+		g.tree, g.treeErr = g.listTree(ctx, g.cfg.CommitSha, "")
+	})
+	return g.tree, g.treeErr
+}
+
+// listTree returns the path of every file in the tree at sha, prefixed with prefix. GitHub truncates the recursive
+// listing of big trees, in which case the entries of the tree are listed, and each of its subtrees in turn.
+func (g *GhClient) listTree(ctx context.Context, sha string, prefix string) ([]string, error) {
+	tree, _, err := g.restClient.Git.GetTree(ctx, g.cfg.RepoOwner, g.cfg.RepoName, sha, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of /%s at %s: %w", prefix, g.cfg.CommitSha, err)
+	}
+	var ret []string
+	if !tree.GetTruncated() {
+		for _, entry := range tree.Entries {
+			if entry.GetType() == "blob" {
+				ret = append(ret, prefix+entry.GetPath())
+			}
+		}
+		return ret, nil
+	}
+	g.logger.Debugf("tree of /%s is truncated, listing its subtrees one at a time", prefix)
+	tree, _, err = g.restClient.Git.GetTree(ctx, g.cfg.RepoOwner, g.cfg.RepoName, sha, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of /%s at %s: %w", prefix, g.cfg.CommitSha, err)
+	}
+	for _, entry := range tree.Entries {
+		switch entry.GetType() {
+		case "blob":
+			ret = append(ret, prefix+entry.GetPath())
+		case "tree":
+			files, err := g.listTree(ctx, entry.GetSHA(), prefix+entry.GetPath()+"/")
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, files...)
+		}
+	}
+	return ret, nil
+}
+
+// CountFiles returns how many files are under dir at the commit
+func (g *GhClient) CountFiles(ctx context.Context, dir string) (int, error) {
+	files, err := g.ListFiles(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dir == "." || dir == "" {
+		return len(files), nil
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var ret int
+	for _, file := range files {
+		if strings.HasPrefix(file, prefix) {
+			ret++
+		}
+	}
+	return ret, nil
+}
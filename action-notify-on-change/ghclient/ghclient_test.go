@@ -0,0 +1,76 @@
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFilesTruncated(t *testing.T) {
+	trees := map[string]string{
+		"abc?recursive=1": `{"sha": "abc", "truncated": true, "tree": [{"path": "a.go", "type": "blob"}]}`,
+		"abc":             `{"sha": "abc", "tree": [{"path": "a.go", "type": "blob"}, {"path": "big", "type": "tree", "sha": "t1"}]}`,
+		"t1?recursive=1":  `{"sha": "t1", "tree": [{"path": "x.go", "type": "blob"}, {"path": "sub", "type": "tree", "sha": "t2"}, {"path": "sub/y.go", "type": "blob"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path[len("/api/v3/repos/cresta/repo/git/trees/"):]
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		tree, exists := trees[key]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, tree)
+	}))
+	defer server.Close()
+	cfg := config.Config{RepoOwner: "cresta", RepoName: "repo", CommitSha: "abc", APIURL: server.URL}
+	restClient, err := newRestClient(cfg, server.Client())
+	require.NoError(t, err)
+	g := &GhClient{restClient: restClient, cfg: cfg, logger: logger.NewTestLogger(t)}
+	files, err := g.ListFiles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "big/x.go", "big/sub/y.go"}, files)
+	count, err := g.CountFiles(context.Background(), "big")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestBaseSha(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/cresta/repo/compare/main-tip...head":
+			_, _ = fmt.Fprint(w, `{"merge_base_commit": {"sha": "merge-base"}}`)
+		case "/api/v3/repos/cresta/repo/git/commits/head":
+			_, _ = fmt.Fprint(w, `{"sha": "head", "parents": [{"sha": "parent"}]}`)
+		case "/api/v3/repos/cresta/repo/git/commits/first":
+			_, _ = fmt.Fprint(w, `{"sha": "first", "parents": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	run := func(cfg config.Config, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			cfg.RepoOwner, cfg.RepoName, cfg.APIURL = "cresta", "repo", server.URL
+			restClient, err := newRestClient(cfg, server.Client())
+			require.NoError(t, err)
+			g := &GhClient{restClient: restClient, cfg: cfg, logger: logger.NewTestLogger(t)}
+			sha, err := g.BaseSha(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, expected, sha)
+		}
+	}
+	t.Run("pull request", run(config.Config{PullRequestNumber: 1, BaseSha: "main-tip", HeadSha: "head"}, "merge-base"))
+	t.Run("push", run(config.Config{BeforeSha: "before", CommitSha: "head"}, "before"))
+	t.Run("new branch", run(config.Config{BeforeSha: "0000000000000000000000000000000000000000", CommitSha: "head"}, "parent"))
+	t.Run("first commit", run(config.Config{CommitSha: "first"}, ""))
+}
@@ -0,0 +1,170 @@
+package ghclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+)
+
+const (
+	// maxRetries is how many times a rate limited or failed request is retried
+	maxRetries = 5
+	// maxRetryWait is the longest the client waits for a rate limit to reset before giving up
+	maxRetryWait = 5 * time.Minute
+	// baseRetryWait is the first wait of the exponential backoff, when GitHub does not say how long to wait
+	baseRetryWait = time.Second
+)
+
+// rateLimitTransport bounds how many requests are in flight at once, and retries requests that hit the primary or
+// secondary rate limits of GitHub, or fail with a server error, after waiting as long as GitHub asks
+type rateLimitTransport struct {
+	next      http.RoundTripper
+	semaphore chan struct{}
+	logger    logger.Logger
+	// sleep waits for d, returning early with an error if ctx is done
+	sleep func(ctx context.Context, d time.Duration) error
+	now   func() time.Time
+
+	mu sync.Mutex
+	// quotas are the last rate limit headers seen for each resource, like core or graphql
+	quotas map[string]quota
+}
+
+type quota struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func newRateLimitTransport(next http.RoundTripper, concurrency int, logger logger.Logger) *rateLimitTransport {
+	return &rateLimitTransport{
+		next:      next,
+		semaphore: make(chan struct{}, concurrency),
+		logger:    logger,
+		sleep:     sleepContext,
+		now:       time.Now,
+		quotas:    make(map[string]quota),
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry request to %s: body cannot be read again", req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read body again for %s: %w", req.URL, err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		resp, err := t.roundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, retry := t.retryWait(resp, attempt)
+		if !retry || attempt == maxRetries {
+			return resp, nil
+		}
+		if wait > maxRetryWait {
+			t.logger.Infof("not retrying %s: GitHub asks to wait %s", req.URL.Path, wait)
+			return resp, nil
+		}
+		t.logger.Infof("retrying %s in %s after status %d", req.URL.Path, wait, resp.StatusCode)
+		_ = resp.Body.Close()
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, fmt.Errorf("stopped waiting to retry %s: %w", req.URL, err)
+		}
+	}
+}
+
+// roundTrip sends the request once there is room for it, and records the rate limit headers of the response
+func (t *rateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.semaphore <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.semaphore }()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.recordQuota(resp)
+	return resp, nil
+}
+
+// retryWait returns how long to wait before retrying the request that got resp, and false if it should not be retried
+func (t *rateLimitTransport) retryWait(resp *http.Response, attempt int) (time.Duration, bool) {
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"))
+	serverError := resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+	if !rateLimited && !serverError {
+		return 0, false
+	}
+	// Secondary rate limits say how long to wait with Retry-After
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	// Primary rate limits say when the quota is reset
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Unix(reset, 0).Sub(t.now()) + time.Second
+			if wait < 0 {
+				wait = 0
+			}
+			return wait, true
+		}
+	}
+	return baseRetryWait << attempt, true
+}
+
+func (t *rateLimitTransport) recordQuota(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.quotas[resource] = quota{limit: limit, remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// logQuotas logs the remaining quota of every rate limited resource the client used
+func (t *rateLimitTransport) logQuotas() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	resources := make([]string, 0, len(t.quotas))
+	for resource := range t.quotas {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		q := t.quotas[resource]
+		t.logger.Infof("github %s rate limit: %d of %d remaining, resets at %s", resource, q.remaining, q.limit, q.reset.Format(time.RFC3339))
+	}
+}
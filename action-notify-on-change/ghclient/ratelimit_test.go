@@ -0,0 +1,79 @@
+package ghclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	run := func(responses []func(w http.ResponseWriter), expectedStatus int, expectedWaits []time.Duration) func(t *testing.T) {
+		return func(t *testing.T) {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				bodies = append(bodies, string(body))
+				responses[len(bodies)-1](w)
+			}))
+			defer server.Close()
+			transport := newRateLimitTransport(http.DefaultTransport, 2, logger.NewTestLogger(t))
+			var waits []time.Duration
+			transport.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			transport.now = func() time.Time { return now }
+			client := &http.Client{Transport: transport}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"query": "q"}`))
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, expectedStatus, resp.StatusCode)
+			assert.Equal(t, expectedWaits, waits)
+			for _, body := range bodies {
+				assert.Equal(t, `{"query": "q"}`, body, "every attempt sends the body")
+			}
+		}
+	}
+	ok := func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.WriteHeader(http.StatusOK)
+	}
+	secondary := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusForbidden)
+	}
+	primary := func(reset time.Time) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}
+	unavailable := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	notFound := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	t.Run("ok", run([]func(w http.ResponseWriter){ok}, http.StatusOK, nil))
+	t.Run("not found", run([]func(w http.ResponseWriter){notFound}, http.StatusNotFound, nil))
+	t.Run("retry after", run([]func(w http.ResponseWriter){secondary, ok}, http.StatusOK, []time.Duration{3 * time.Second}))
+	t.Run("rate limit reset", run([]func(w http.ResponseWriter){primary(now.Add(time.Minute)), ok}, http.StatusOK, []time.Duration{time.Minute + time.Second}))
+	t.Run("reset too late", run([]func(w http.ResponseWriter){primary(now.Add(time.Hour))}, http.StatusForbidden, nil))
+	t.Run("backoff", run([]func(w http.ResponseWriter){unavailable, unavailable, ok}, http.StatusOK, []time.Duration{time.Second, 2 * time.Second}))
+	t.Run("gives up", run([]func(w http.ResponseWriter){unavailable, unavailable, unavailable, unavailable, unavailable, unavailable}, http.StatusServiceUnavailable,
+		[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}))
+}
@@ -0,0 +1,78 @@
+package gitattributes
+
+import (
+	"strings"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// Attributes is a parsed .gitattributes file
+type Attributes struct {
+	lines []line
+}
+
+type line struct {
+	matcher *ignore.GitIgnore
+	// attributes maps an attribute name to its state: "true" when set, "false" when unset (-attr), "" when
+	// unspecified (!attr), or its value
+	attributes map[string]string
+}
+
+// Parse parses the content of a .gitattributes file. Lines that cannot be understood are ignored, like git does.
+func Parse(content []byte) *Attributes {
+	ret := &Attributes{}
+	for _, rawLine := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(rawLine)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		attributes := make(map[string]string, len(fields)-1)
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "-"):
+				attributes[field[1:]] = "false"
+			case strings.HasPrefix(field, "!"):
+				attributes[field[1:]] = ""
+			case strings.Contains(field, "="):
+				kv := strings.SplitN(field, "=", 2)
+				attributes[kv[0]] = kv[1]
+			default:
+				attributes[field] = "true"
+			}
+		}
+		ret.lines = append(ret.lines, line{
+			matcher:    ignore.CompileIgnoreLines(fields[0]),
+			attributes: attributes,
+		})
+	}
+	return ret
+}
+
+// Get returns the state of attribute for path. As in git, the last matching line wins.
+func (a *Attributes) Get(path string, attribute string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	for idx := len(a.lines) - 1; idx >= 0; idx-- {
+		value, exists := a.lines[idx].attributes[attribute]
+		if exists && a.lines[idx].matcher.MatchesPath(path) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (a *Attributes) isSet(path string, attribute string) bool {
+	value, exists := a.Get(path, attribute)
+	return exists && value != "false" && value != ""
+}
+
+// IsGenerated returns true if path is marked linguist-generated
+func (a *Attributes) IsGenerated(path string) bool {
+	return a.isSet(path, "linguist-generated")
+}
+
+// IsVendored returns true if path is marked linguist-vendored
+func (a *Attributes) IsVendored(path string) bool {
+	return a.isSet(path, "linguist-vendored")
+}
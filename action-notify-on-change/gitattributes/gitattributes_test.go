@@ -0,0 +1,25 @@
+package gitattributes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributes(t *testing.T) {
+	attrs := Parse([]byte(`# comment
+*.pb.go linguist-generated
+vendor/** linguist-vendored
+api/keep.pb.go -linguist-generated
+*.lock linguist-generated=true
+docs/** !linguist-generated
+`))
+	assert.True(t, attrs.IsGenerated("api/service.pb.go"))
+	assert.False(t, attrs.IsGenerated("api/keep.pb.go"))
+	assert.False(t, attrs.IsGenerated("api/service.go"))
+	assert.True(t, attrs.IsGenerated("yarn.lock"))
+	assert.True(t, attrs.IsVendored("vendor/github.com/lib/lib.go"))
+	assert.False(t, attrs.IsVendored("api/service.pb.go"))
+	assert.False(t, attrs.IsGenerated("docs/gen.pb.go"))
+	assert.False(t, Parse(nil).IsGenerated("a.go"))
+}
@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+)
+
+// NewTransport returns the transport the GitHub and Slack clients send requests with. It trusts the certificates of
+// the CA bundle on top of the system ones, and sends requests through the configured proxy, or the proxy of the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+func NewTransport(cfg config.Config) (*http.Transport, error) {
+	ret := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.HTTPProxy != "" {
+		proxyURL, err := url.Parse(cfg.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse http proxy %s: %w", cfg.HTTPProxy, err)
+		}
+		ret.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.CABundle != "" {
+		rootCAs, err := loadCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		ret.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return ret, nil
+}
+
+// loadCABundle returns the system certificates together with the PEM certificates of the file at path
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca bundle %s: %w", path, err)
+	}
+	ret, err := x509.SystemCertPool()
+	if err != nil {
+		ret = x509.NewCertPool()
+	}
+	if !ret.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca bundle %s has no PEM certificates", path)
+	}
+	return ret, nil
+}
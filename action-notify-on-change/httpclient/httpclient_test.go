@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	run := func(cfg config.Config, expectErr bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			transport, err := NewTransport(cfg)
+			require.NoError(t, err)
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		}
+	}
+	t.Run("untrusted", run(config.Config{}, true))
+	t.Run("ca bundle", run(config.Config{CABundle: caBundle}, false))
+
+	_, err := NewTransport(config.Config{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	require.Error(t, err)
+	_, err = NewTransport(config.Config{HTTPProxy: "://bad"})
+	require.Error(t, err)
+}
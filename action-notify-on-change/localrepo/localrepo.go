@@ -0,0 +1,317 @@
+package localrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// Repo reads the repository checked out in the workspace, instead of asking the GitHub API
+type Repo struct {
+	cfg    config.Config
+	logger logger.Logger
+
+	openOnce sync.Once
+	repo     *git.Repository
+	openErr  error
+
+	filesOnce sync.Once
+	files     []string
+	filesErr  error
+
+	// fromHistory reads files from the commit in git history instead of from the checkout
+	fromHistory bool
+}
+
+func New(cfg config.Config, logger logger.Logger) *Repo {
+	return &Repo{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// At returns a Repo that reads files at sha from the git history, instead of from the checkout
+func (r *Repo) At(sha string) *Repo {
+	cfg := r.cfg
+	cfg.CommitSha = sha
+	return &Repo{
+		cfg:         cfg,
+		logger:      r.logger,
+		fromHistory: true,
+	}
+}
+
+func (r *Repo) open() (*git.Repository, error) {
+	r.openOnce.Do(func() {
+		r.repo, r.openErr = git.PlainOpen(r.cfg.Workspace)
+		if r.openErr != nil {
+			r.openErr = fmt.Errorf("failed to open repository in %s: %w", r.cfg.Workspace, r.openErr)
+		}
+	})
+	return r.repo, r.openErr
+}
+
+// GetContents returns the content of the checked out file at filePath, or nil if there is no such file
+func (r *Repo) GetContents(_ context.Context, filePath string) ([]byte, error) {
+	if !IsInside(filePath) {
+		return nil, fmt.Errorf("path %s is outside of the repository", filePath)
+	}
+	if r.fromHistory {
+		return r.getContentsFromHistory(filePath)
+	}
+	r.logger.Debugf("reading %s", filePath)
+	fullPath, err := r.resolve(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
+		// A directory: ignore it
+		return nil, nil
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return content, nil
+}
+
+// resolve returns the path of filePath in the workspace, with its symbolic links followed. Links pointing outside of
+// the workspace are rejected, as a pull request could add them to read any file of the runner.
+func (r *Repo) resolve(filePath string) (string, error) {
+	workspace, err := filepath.EvalSymlinks(r.cfg.Workspace)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace %s: %w", r.cfg.Workspace, err)
+	}
+	fullPath, err := filepath.EvalSymlinks(filepath.Join(workspace, filepath.FromSlash(filePath)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", filePath, err)
+	}
+	relPath, err := filepath.Rel(workspace, fullPath)
+	if err != nil || !IsInside(relPath) {
+		return "", fmt.Errorf("path %s links outside of the repository", filePath)
+	}
+	return fullPath, nil
+}
+
+// IsInside returns true if filePath, relative to the root of the repository, does not point outside of it
+func IsInside(filePath string) bool {
+	cleaned := filepath.Clean(filepath.FromSlash(filePath))
+	return !filepath.IsAbs(cleaned) && cleaned != ".." && !strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+func (r *Repo) getContentsFromHistory(filePath string) ([]byte, error) {
+	r.logger.Debugf("reading %s at %s", filePath, r.cfg.CommitSha)
+	commit, err := r.commit(r.cfg.CommitSha)
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(filepath.ToSlash(filepath.Clean(filePath)))
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s at %s: %w", filePath, r.cfg.CommitSha, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", filePath, r.cfg.CommitSha, err)
+	}
+	return []byte(content), nil
+}
+
+// ListFiles returns the path of every file in the repository at the commit. It is only read once.
+func (r *Repo) ListFiles(_ context.Context) ([]string, error) {
+	r.filesOnce.Do(func() {
+		commit, err := r.commit(r.cfg.CommitSha)
+		if err != nil {
+			r.filesErr = err
+			return
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			r.filesErr = fmt.Errorf("failed to get tree of %s: %w", commit.Hash, err)
+			return
+		}
+		r.filesErr = tree.Files().ForEach(func(file *object.File) error {
+			r.files = append(r.files, file.Name)
+			return nil
+		})
+	})
+	return r.files, r.filesErr
+}
+
+// CountFiles returns how many files are under dir at the commit
+func (r *Repo) CountFiles(ctx context.Context, dir string) (int, error) {
+	files, err := r.ListFiles(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dir == "." || dir == "" {
+		return len(files), nil
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var ret int
+	for _, file := range files {
+		if strings.HasPrefix(file, prefix) {
+			ret++
+		}
+	}
+	return ret, nil
+}
+
+func (r *Repo) commit(sha string) (*object.Commit, error) {
+	repo, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find commit %s, is the repository checked out with its history (fetch-depth: 0)?: %w", sha, err)
+	}
+	return commit, nil
+}
+
+// HeadCommit returns the commit a pull request comes from, or the commit that was pushed
+func (r *Repo) HeadCommit() (*object.Commit, error) {
+	if r.cfg.PullRequestNumber != 0 && r.cfg.HeadSha != "" {
+		return r.commit(r.cfg.HeadSha)
+	}
+	return r.commit(r.cfg.CommitSha)
+}
+
+// baseCommit returns the commit to compare the head commit with: where a pull request branched from its base, or
+// what the branch pointed to before a push. It returns nil for the first commit of a repository.
+func (r *Repo) baseCommit(head *object.Commit) (*object.Commit, error) {
+	if r.cfg.PullRequestNumber != 0 {
+		base, err := r.commit(r.cfg.BaseSha)
+		if err != nil {
+			return nil, err
+		}
+		mergeBases, err := base.MergeBase(head)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find merge base of %s and %s: %w", base.Hash, head.Hash, err)
+		}
+		if len(mergeBases) == 0 {
+			return nil, fmt.Errorf("%s and %s have no common ancestor, is the repository checked out with its history (fetch-depth: 0)?", base.Hash, head.Hash)
+		}
+		return mergeBases[0], nil
+	}
+	if r.cfg.BeforeSha != "" && strings.Trim(r.cfg.BeforeSha, "0") != "" {
+		return r.commit(r.cfg.BeforeSha)
+	}
+	// A new branch: compare with the parent of the pushed commit
+	if head.NumParents() == 0 {
+		return nil, nil
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find parent of %s, is the repository checked out with its history (fetch-depth: 0)?: %w", head.Hash, err)
+	}
+	return parent, nil
+}
+
+// BaseSha returns the SHA of the commit the head commit is compared with, or "" for the first commit of a repository
+func (r *Repo) BaseSha(_ context.Context) (string, error) {
+	head, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	base, err := r.baseCommit(head)
+	if err != nil || base == nil {
+		return "", err
+	}
+	return base.Hash.String(), nil
+}
+
+// ChangedFiles returns the files changed between the base and head commits, with their patch and line counts
+func (r *Repo) ChangedFiles(ctx context.Context) ([]ghclient.ChangedFile, error) {
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	base, err := r.baseCommit(head)
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", head.Hash, err)
+	}
+	var baseTree *object.Tree
+	if base != nil {
+		if baseTree, err = base.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get tree of %s: %w", base.Hash, err)
+		}
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, baseTree, headTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s with its base: %w", head.Hash, err)
+	}
+	ret := make([]ghclient.ChangedFile, 0, len(changes))
+	for _, change := range changes {
+		changedFile, err := newChangedFile(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, changedFile)
+	}
+	return ret, nil
+}
+
+// newChangedFile describes change the way the GitHub API does
+func newChangedFile(ctx context.Context, change *object.Change) (ghclient.ChangedFile, error) {
+	action, err := change.Action()
+	if err != nil {
+		return ghclient.ChangedFile{}, fmt.Errorf("failed to get action of %s: %w", change, err)
+	}
+	ret := ghclient.ChangedFile{Filename: change.To.Name}
+	switch {
+	case action == merkletrie.Insert:
+		ret.Status = "added"
+	case action == merkletrie.Delete:
+		ret.Status = "removed"
+		ret.Filename = change.From.Name
+	case change.From.Name != change.To.Name:
+		ret.Status = "renamed"
+		ret.PreviousFilename = change.From.Name
+	default:
+		ret.Status = "modified"
+	}
+	patch, err := change.PatchContext(ctx)
+	if err != nil {
+		return ghclient.ChangedFile{}, fmt.Errorf("failed to get patch of %s: %w", ret.Filename, err)
+	}
+	for _, stat := range patch.Stats() {
+		ret.Additions += stat.Addition
+		ret.Deletions += stat.Deletion
+	}
+	ret.Patch = hunks(patch.String())
+	return ret, nil
+}
+
+// hunks drops the headers of a unified diff, as GitHub patches start at the first hunk
+func hunks(patch string) string {
+	if idx := strings.Index(patch, "\n@@"); idx >= 0 {
+		return patch[idx+1:]
+	}
+	return ""
+}
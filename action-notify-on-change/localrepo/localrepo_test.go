@@ -0,0 +1,75 @@
+package localrepo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo/localrepotest"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	repo := localrepotest.New(t)
+	dir := repo.Dir
+	base := repo.Commit(map[string]string{"db/schema.sql": "create table a;\n", "old/name.go": "package name\n\nfunc A() {}\n"})
+	head := repo.Commit(map[string]string{"db/schema.sql": "create table a;\ncreate table b;\n", "new/name.go": "package name\n\nfunc A() {}\n"}, "old/name.go")
+	// The base branch moves on after the pull request branched from it
+	require.NoError(t, repo.Worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(base), Branch: plumbing.NewBranchReferenceName("main"), Create: true}))
+	movedBase := repo.Commit(map[string]string{"README.md": "readme\n"})
+
+	expected := []ghclient.ChangedFile{
+		{Filename: "db/schema.sql", Status: "modified", Additions: 1, Patch: "@@ -1 +1,2 @@\n create table a;\n+create table b;\n"},
+		{Filename: "new/name.go", Status: "renamed", PreviousFilename: "old/name.go"},
+	}
+	run := func(cfg config.Config) func(t *testing.T) {
+		return func(t *testing.T) {
+			cfg.Workspace = dir
+			files, err := New(cfg, logger.NewTestLogger(t)).ChangedFiles(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, expected, files)
+		}
+	}
+	t.Run("push", run(config.Config{CommitSha: head, BeforeSha: base}))
+	t.Run("new branch", run(config.Config{CommitSha: head, BeforeSha: "0000000000000000000000000000000000000000"}))
+	t.Run("pull request", run(config.Config{PullRequestNumber: 1, BaseSha: movedBase, HeadSha: head}))
+
+	atBase := New(config.Config{Workspace: dir}, logger.NewTestLogger(t)).At(base)
+	content, err := atBase.GetContents(context.Background(), "db/schema.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "create table a;\n", string(content), "reads the commit, not the checkout")
+	content, err = atBase.GetContents(context.Background(), "README.md")
+	require.NoError(t, err)
+	assert.Nil(t, content)
+}
+
+func TestGetContentsOutsideWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret"), []byte("secret\n"), 0o600))
+	workspace := filepath.Join(dir, "workspace")
+	require.NoError(t, os.Mkdir(workspace, 0o755))
+	repo := New(config.Config{Workspace: workspace}, logger.NewTestLogger(t))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret"), filepath.Join(workspace, "link")))
+	require.NoError(t, os.Symlink("..", filepath.Join(workspace, "parent")))
+	for _, path := range []string{"../secret", "a/../../secret", filepath.Join(dir, "secret"), "link", "parent/secret"} {
+		content, err := repo.GetContents(context.Background(), path)
+		assert.ErrorContains(t, err, "outside of the repository", path)
+		assert.Nil(t, content)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "a.yaml"), []byte("a\n"), 0o600))
+	require.NoError(t, os.Symlink("a.yaml", filepath.Join(workspace, "inside")))
+	content, err := repo.GetContents(context.Background(), "inside")
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(content), "links inside the repository are followed")
+	content, err = repo.GetContents(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.Nil(t, content)
+}
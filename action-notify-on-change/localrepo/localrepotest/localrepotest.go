@@ -0,0 +1,50 @@
+// Package localrepotest creates git repositories for tests
+package localrepotest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+// Repo is a git repository in a temporary directory
+type Repo struct {
+	Dir      string
+	Worktree *git.Worktree
+	t        *testing.T
+}
+
+func New(t *testing.T) *Repo {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	return &Repo{
+		Dir:      dir,
+		Worktree: worktree,
+		t:        t,
+	}
+}
+
+// Commit writes files, removes the removed ones, and commits them. It returns the SHA of the commit.
+func (r *Repo) Commit(files map[string]string, removed ...string) string {
+	for name, content := range files {
+		require.NoError(r.t, os.MkdirAll(filepath.Join(r.Dir, filepath.Dir(name)), 0o755))
+		require.NoError(r.t, os.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0o600))
+		_, err := r.Worktree.Add(name)
+		require.NoError(r.t, err)
+	}
+	for _, name := range removed {
+		_, err := r.Worktree.Remove(name)
+		require.NoError(r.t, err)
+	}
+	hash, err := r.Worktree.Commit("change", &git.CommitOptions{Author: &object.Signature{Name: "someone", When: time.Now()}})
+	require.NoError(r.t, err)
+	return hash.String()
+}
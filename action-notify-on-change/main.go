@@ -10,6 +10,8 @@ import (
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/changetosend"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/httpclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 
@@ -29,8 +31,12 @@ var moduleMainSetup = fx.Module("main-setup", fx.Options(
 		newAction,
 		actionlogic.New,
 		ghclient.New,
+		httpclient.NewTransport,
 		fx.Annotate(changetosend.NewSlackDestination, fx.As(new(changetosend.Sender))),
-		fx.Annotate(annotatedinfo.NewFromGh, fx.As(new(annotatedinfo.Fetch))),
+		annotatedinfo.NewFromGh,
+		annotatedinfo.NewFromLocal,
+		annotatedinfo.New,
+		localrepo.New,
 		changetosend.NewCreator,
 		notification.NewMerger,
 		notification.NewLoader,
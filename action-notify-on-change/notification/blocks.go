@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBlocks is the most blocks Slack accepts in a message
+const maxBlocks = 50
+
+// FindBlocksTemplate returns the blocksTemplate of the closest file that sets one
+func (f *File) FindBlocksTemplate() string {
+	for layer := f; layer != nil; layer = layer.Parent {
+		if layer.BlocksTemplate != "" {
+			return layer.BlocksTemplate
+		}
+	}
+	return ""
+}
+
+// RenderBlocks executes blocksTemplate with data and decodes its output, either a JSON or YAML list of Block Kit
+// blocks or an object with a blocks key, into Slack blocks.
+func RenderBlocks(blocksTemplate string, data interface{}) ([]slack.Block, error) {
+	t, err := newTemplate(blocksTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse blocks template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to execute blocks template: %w", err)
+	}
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		return nil, fmt.Errorf("blocks template output is not valid JSON or YAML: %w", err)
+	}
+	if asMap, ok := decoded.(map[string]interface{}); ok {
+		decoded = asMap["blocks"]
+	}
+	if _, ok := decoded.([]interface{}); !ok {
+		return nil, fmt.Errorf("blocks template output must be a list of blocks or an object with a blocks list")
+	}
+	asJSON, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert blocks to JSON: %w", err)
+	}
+	var blocks slack.Blocks
+	if err := json.Unmarshal(asJSON, &blocks); err != nil {
+		return nil, fmt.Errorf("invalid blocks: %w", err)
+	}
+	if len(blocks.BlockSet) == 0 {
+		return nil, fmt.Errorf("blocks template rendered no blocks")
+	}
+	if len(blocks.BlockSet) > maxBlocks {
+		return nil, fmt.Errorf("blocks template rendered %d blocks, Slack accepts at most %d", len(blocks.BlockSet), maxBlocks)
+	}
+	for idx, block := range blocks.BlockSet {
+		if _, ok := block.(*slack.UnknownBlock); ok {
+			return nil, fmt.Errorf("block %d: unknown block type %q", idx, block.BlockType())
+		}
+	}
+	return blocks.BlockSet, nil
+}
@@ -0,0 +1,44 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderBlocks(t *testing.T) {
+	data := struct{ Title string }{Title: `Fix "quotes" & <links>`}
+	run := func(blocksTemplate string, expectedTypes ...slack.MessageBlockType) func(t *testing.T) {
+		return func(t *testing.T) {
+			blocks, err := RenderBlocks(blocksTemplate, data)
+			if len(expectedTypes) == 0 {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			actualTypes := make([]slack.MessageBlockType, 0, len(blocks))
+			for _, block := range blocks {
+				actualTypes = append(actualTypes, block.BlockType())
+			}
+			assert.Equal(t, expectedTypes, actualTypes)
+		}
+	}
+	t.Run("json list", run(`[{"type": "section", "text": {"type": "mrkdwn", "text": {{ .Title | toJSON }}}}, {"type": "divider"}]`, slack.MBTSection, slack.MBTDivider))
+	t.Run("json object", run(`{"blocks": [{"type": "divider"}]}`, slack.MBTDivider))
+	t.Run("yaml", run("- type: header\n  text:\n    type: plain_text\n    text: {{ .Title | toJSON }}\n", slack.MBTHeader))
+	t.Run("not a list", run(`{"type": "divider"}`))
+	t.Run("empty", run(`[]`))
+	t.Run("unknown block", run(`[{"type": "banner"}]`))
+	t.Run("invalid output", run(`[{"type": "divider"`))
+	t.Run("missing field", run(`[{{ .Missing }}]`))
+}
+
+func TestFindBlocksTemplate(t *testing.T) {
+	root := &File{BlocksTemplate: "root"}
+	child := &File{Parent: root}
+	assert.Equal(t, "root", child.FindBlocksTemplate())
+	child.BlocksTemplate = "child"
+	assert.Equal(t, "child", child.FindBlocksTemplate())
+}
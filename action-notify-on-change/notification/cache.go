@@ -0,0 +1,35 @@
+package notification
+
+import (
+	"sync"
+)
+
+// onceCache loads the value of each key once per run. Concurrent callers asking for the same key wait for the first
+// one to finish loading it, and then share its result, error included.
+type onceCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*onceEntry[T]
+}
+
+type onceEntry[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+func (c *onceCache[T]) get(key string, load func() (T, error)) (T, error) {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*onceEntry[T])
+	}
+	entry, exists := c.entries[key]
+	if !exists {
+		entry = &onceEntry[T]{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+	entry.once.Do(func() {
+		entry.value, entry.err = load()
+	})
+	return entry.value, entry.err
+}
@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnceCache(t *testing.T) {
+	var cache onceCache[int]
+	var loads atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.get("a", func() (int, error) {
+				loads.Add(1)
+				return 1, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+
+	failure := errors.New("failure")
+	_, err := cache.get("b", func() (int, error) { return 0, failure })
+	assert.ErrorIs(t, err, failure)
+	_, err = cache.get("b", func() (int, error) { return 2, nil })
+	assert.ErrorIs(t, err, failure, "errors are cached for the run too")
+}
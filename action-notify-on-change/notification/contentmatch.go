@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/stringhelper"
+)
+
+// matchPatch returns the added or removed lines of patch, a unified diff, that match one of the regexes
+func matchPatch(regexes []string, patch string) ([]string, error) {
+	compiled := make([]*regexp.Regexp, 0, len(regexes))
+	for _, rgx := range regexes {
+		c, err := regexp.Compile(rgx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %w", rgx, err)
+		}
+		compiled = append(compiled, c)
+	}
+	var ret []string
+	// Lines before the first hunk are file headers, like --- a/file, which GitHub patches do not have
+	var inHunk bool
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			continue
+		}
+		if !inHunk || (!strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-")) {
+			continue
+		}
+		for _, c := range compiled {
+			if c.MatchString(line[1:]) {
+				ret = append(ret, line)
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// AllMatchedLines returns the lines of the patch that matched the contentMatch regexes of the file and its parents.
+// It is only set on files returned by WithConditions.
+func (f *File) AllMatchedLines(changeType config.ChangeType) []string {
+	if f == nil {
+		return nil
+	}
+	return stringhelper.Deduplicate(append(append([]string{}, f.matchedLines...), f.Parent.AllMatchedLines(changeType)...))
+}
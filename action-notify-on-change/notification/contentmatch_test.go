@@ -0,0 +1,22 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPatch(t *testing.T) {
+	run := func(regexes []string, patch string, expected []string) func(t *testing.T) {
+		return func(t *testing.T) {
+			matched, err := matchPatch(regexes, patch)
+			require.NoError(t, err)
+			assert.Equal(t, expected, matched)
+		}
+	}
+	t.Run("removed sql comment", run([]string{"DROP TABLE"}, "@@ -1,2 +1 @@\n-- DROP TABLE users;\n SELECT 1;\n", []string{"-- DROP TABLE users;"}))
+	t.Run("added increment", run([]string{`^\+\+counter`}, "@@ -1 +1,2 @@\n x := 0\n+++counter\n", []string{"+++counter"}))
+	t.Run("file headers", run([]string{"file"}, "--- a/file\n+++ b/file\n@@ -1 +1 @@\n-old file\n+new\n", []string{"-old file"}))
+	t.Run("context lines", run([]string{"DROP"}, "@@ -1 +1 @@\n DROP\n", nil))
+}
@@ -2,8 +2,6 @@ package notification
 
 import (
 	"fmt"
-	"html/template"
-	"strings"
 
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 
@@ -11,57 +9,199 @@ import (
 )
 
 type File struct {
-	PullRequest     Notification `yaml:"pullRequest,omitempty"`
-	Commit          Notification `yaml:"commit,omitempty"`
-	PrettyName      []string     `yaml:"prettyName,omitempty"`
-	MessageTemplate string       `yaml:"messageTemplate,omitempty"`
+	Sections        `yaml:",inline"`
+	PrettyName      []string `yaml:"prettyName,omitempty"`
+	MessageTemplate string   `yaml:"messageTemplate,omitempty"`
+	// Format of the output of MessageTemplate: mrkdwn, the default, or markdown
+	Format MessageFormat `yaml:"format,omitempty"`
+	// MessageMode is how the message of this file is combined with the one of its parents: append, the default,
+	// prepend, replace or section
+	MessageMode MessageMode `yaml:"messageMode,omitempty"`
+	// BlocksTemplate is a template of Block Kit blocks, in JSON or YAML, that replaces the default layout of the Slack
+	// message. Files in subdirectories inherit it.
+	BlocksTemplate string `yaml:"blocksTemplate,omitempty"`
+	// Rules narrow notifications to only some of the files under this directory
+	Rules []Rule `yaml:"rules,omitempty"`
+	// Ignore lists glob patterns, relative to this directory, of files that never trigger notifications
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Inherit, if set to false, stops looking for notification files in parent directories
+	Inherit *bool `yaml:"inherit,omitempty"`
+	// Root is the same as inherit: false
+	Root bool `yaml:"root,omitempty"`
+	// Teams can be referenced by name from any notification. They are only read from the file at the root of the
+	// repository, or the files it includes.
+	Teams map[string]Team `yaml:"teams,omitempty"`
+	// Include lists other yaml files, relative to the root of the repository, that are merged into this one. Values
+	// set in this file win over the included ones.
+	Include []string `yaml:"include,omitempty"`
 	// Parent is the notification file in the Parent directory. If there is none, it's an empty file.
 	Parent      *File  `yaml:"-"` // This is used to allow us to merge the Parent with the child
 	ChangedFile string `yaml:"-"` // Which files were changed that caused this notification file to be used
+	// conditionsFailed is set by WithConditions when the when block of the section does not match the change
+	conditionsFailed bool
+	// dir is the directory of the notification file, set by Merger
+	dir string
+	// rule is the rule this file was created from, if any
+	rule *Rule
+	// matchedLines are the lines of the patch that matched contentMatch, set by WithConditions
+	matchedLines []string
 }
 
 type Notification struct {
 	// Which Slack channel to notify on a change
 	Channel string `yaml:"channel,omitempty"`
+	// More Slack channels to notify, each with their own subscribers
+	Channels []ChannelTarget `yaml:"channels,omitempty"`
+	// Name of a team whose subscribers, and channel if none is set, are added to this notification
+	Team string `yaml:"team,omitempty"`
 	// Which users to tag in the notification
-	Users           []string `yaml:"users,omitempty"`
-	Groups          []string `yaml:"groups,omitempty"`
-	MessageTemplate string   `yaml:"messageTemplate,omitempty"`
+	Users           Subscribers `yaml:"users,omitempty"`
+	Groups          Subscribers `yaml:"groups,omitempty"`
+	MessageTemplate string      `yaml:"messageTemplate,omitempty"`
+	// Format of the output of MessageTemplate: mrkdwn, the default, or markdown
+	Format MessageFormat `yaml:"format,omitempty"`
+	// What to do with files marked linguist-generated or linguist-vendored in .gitattributes
+	GeneratedFiles GeneratedFilesMode `yaml:"generatedFiles,omitempty"`
+	// Only notify when the change matches these conditions
+	When *When `yaml:"when,omitempty"`
+	// Only notify when an added or removed line of the file matches one of these regular expressions
+	ContentMatch []string `yaml:"contentMatch,omitempty"`
 }
 
-func (f *File) ProcessTemplate(changeType config.ChangeType) (string, error) {
+// ChannelTarget is one of several Slack channels to notify. Its users and groups are added to the ones of the
+// notification, or replace them with mode: replace. Its template is added to the message for this channel only.
+type ChannelTarget struct {
+	Channel         string        `yaml:"channel,omitempty"`
+	Team            string        `yaml:"team,omitempty"`
+	Users           Subscribers   `yaml:"users,omitempty"`
+	Groups          Subscribers   `yaml:"groups,omitempty"`
+	MessageTemplate string        `yaml:"messageTemplate,omitempty"`
+	Format          MessageFormat `yaml:"format,omitempty"`
+}
+
+// asFile turns the channel into a notification file layered on top of parent
+func (c *ChannelTarget) asFile(parent *File, changeType config.ChangeType) *File {
+	ret := &File{
+		PrettyName:  parent.PrettyName,
+		Parent:      parent,
+		ChangedFile: parent.ChangedFile,
+		dir:         parent.dir,
+	}
+	*ret.section(changeType) = Notification{
+		Channel:         c.Channel,
+		Users:           c.Users,
+		Groups:          c.Groups,
+		MessageTemplate: c.MessageTemplate,
+		Format:          c.Format,
+	}
+	return ret
+}
+
+type MessageMode string
+
+const (
+	// MessageModeAppend adds the message after the one of the parents, separated by " - "
+	MessageModeAppend MessageMode = "append"
+	// MessageModePrepend adds the message before the one of the parents, separated by " - "
+	MessageModePrepend MessageMode = "prepend"
+	// MessageModeReplace drops the message of the parents, even without a template of its own. Templates can still
+	// use it as {{ .ParentMessage }}.
+	MessageModeReplace MessageMode = "replace"
+	// MessageModeSection puts the message on its own line, after the one of the parents
+	MessageModeSection MessageMode = "section"
+)
+
+// combine returns the message of a file combined with the message of its parents
+func (m MessageMode) combine(parentMessage string, message string) string {
+	if parentMessage == "" || m == MessageModeReplace {
+		return message
+	}
+	switch m {
+	case MessageModePrepend:
+		return message + " - " + parentMessage
+	case MessageModeSection:
+		return parentMessage + "\n" + message
+	default:
+		return parentMessage + " - " + message
+	}
+}
+
+type GeneratedFilesMode string
+
+const (
+	// GeneratedFilesInclude treats generated files like any other file
+	GeneratedFilesInclude GeneratedFilesMode = "include"
+	// GeneratedFilesCollapse only shows how many generated files changed
+	GeneratedFilesCollapse GeneratedFilesMode = "collapse"
+	// GeneratedFilesSkip never notifies for generated files
+	GeneratedFilesSkip GeneratedFilesMode = "skip"
+)
+
+// StopsInheritance returns true if notification files in parent directories should be ignored
+func (f *File) StopsInheritance() bool {
+	if f == nil {
+		return false
+	}
+	return f.Root || (f.Inherit != nil && !*f.Inherit)
+}
+
+// ProcessTemplate executes the message templates of the file and its parents with data, parents first, and combines
+// them according to the messageMode of each file
+func (f *File) ProcessTemplate(changeType config.ChangeType, data TemplateContext) (string, error) {
 	if f == nil {
 		return "", nil
 	}
-	parentTemplate, err := f.Parent.ProcessTemplate(changeType)
+	parentTemplate, err := f.Parent.ProcessTemplate(changeType, data)
 	if err != nil {
 		return "", fmt.Errorf("failed to process Parent template: %w", err)
 	}
-	var messageTemplate string
-	if changeType == config.ChangeTypeCommit {
-		messageTemplate = f.Commit.MessageTemplate
-	} else {
-		messageTemplate = f.PullRequest.MessageTemplate
+	if f.conditionsFailed {
+		return parentTemplate, nil
 	}
+	section := f.section(changeType)
+	messageTemplate, format := section.MessageTemplate, section.Format
 	if messageTemplate == "" {
-		messageTemplate = f.MessageTemplate
+		messageTemplate, format = f.MessageTemplate, f.Format
 	}
 	if messageTemplate == "" {
+		if f.MessageMode == MessageModeReplace {
+			// Replacing the message of the parents without a template of its own removes the message
+			return "", nil
+		}
 		return parentTemplate, nil
 	}
-	t, err := template.New("message").Parse(messageTemplate)
+	data.File = f
+	data.PrettyName = f.PrettyName
+	data.ParentMessage = parentTemplate
+	ret, err := executeTemplate(messageTemplate, format, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template %s: %w", f.MessageTemplate, err)
+		return "", err
 	}
-	var b strings.Builder
-	if err := t.Execute(&b, f); err != nil {
-		return "", fmt.Errorf("failed to execute template %s: %w", f.MessageTemplate, err)
+	return f.MessageMode.combine(parentTemplate, ret), nil
+}
+
+// TemplateKey identifies the message templates ProcessTemplate executes for changeType. Files with the same key render
+// the same message for the same data.
+func (f *File) TemplateKey(changeType config.ChangeType) string {
+	if f == nil {
+		return ""
 	}
-	ret := b.String()
-	if parentTemplate != "" {
-		ret = parentTemplate + " - " + ret
+	parentKey := f.Parent.TemplateKey(changeType)
+	if f.conditionsFailed {
+		return parentKey
 	}
-	return ret, nil
+	section := f.section(changeType)
+	messageTemplate, format := section.MessageTemplate, section.Format
+	if messageTemplate == "" {
+		messageTemplate, format = f.MessageTemplate, f.Format
+	}
+	if messageTemplate == "" {
+		if f.MessageMode == MessageModeReplace {
+			return ""
+		}
+		return parentKey
+	}
+	return fmt.Sprintf("%s\x00%q|%s|%s|%q", parentKey, messageTemplate, format, f.MessageMode, f.PrettyName)
 }
 
 func (f *File) AllUsers(changeType config.ChangeType) []string {
@@ -69,7 +209,7 @@ func (f *File) AllUsers(changeType config.ChangeType) []string {
 		return nil
 	}
 	users := f.Users(changeType)
-	if f.Parent != nil {
+	if f.Parent != nil && f.section(changeType).Users.Mode != MergeModeReplace {
 		users = append(users, f.Parent.AllUsers(changeType)...)
 	}
 	return stringhelper.Deduplicate(users)
@@ -80,7 +220,7 @@ func (f *File) AllGroups(changeType config.ChangeType) []string {
 		return nil
 	}
 	groups := f.Groups(changeType)
-	if f.Parent != nil {
+	if f.Parent != nil && f.section(changeType).Groups.Mode != MergeModeReplace {
 		groups = append(groups, f.Parent.AllGroups(changeType)...)
 	}
 	return stringhelper.Deduplicate(groups)
@@ -90,50 +230,97 @@ func (f *File) Users(changeType config.ChangeType) []string {
 	if f == nil {
 		return nil
 	}
-	switch changeType {
-	case config.ChangeTypeCommit:
-		return f.Commit.Users
-	case config.ChangeTypePullRequest:
-		return f.PullRequest.Users
-	default:
-		panic("unknown change type")
-	}
+	return f.section(changeType).Users.Values
 }
 
 func (f *File) Groups(changeType config.ChangeType) []string {
 	if f == nil {
 		return nil
 	}
-	switch changeType {
-	case config.ChangeTypeCommit:
-		return f.Commit.Groups
-	case config.ChangeTypePullRequest:
-		return f.PullRequest.Groups
-	default:
-		panic("unknown change type")
+	return f.section(changeType).Groups.Values
+}
+
+// section returns the part of the file that applies to changeType
+func (f *File) section(changeType config.ChangeType) *Notification {
+	if f.conditionsFailed {
+		return &Notification{}
 	}
+	return f.Sections.ForChangeType(changeType)
 }
 
 func (f *File) Channel(changeType config.ChangeType) string {
 	if f == nil {
 		return ""
 	}
-	switch changeType {
-	case config.ChangeTypeCommit:
-		if f.Commit.Channel != "" {
-			return f.Commit.Channel
+	if channel := f.section(changeType).Channel; channel != "" {
+		return channel
+	}
+	return f.Parent.Channel(changeType)
+}
+
+// Destinations returns one notification file per Slack channel to notify. They come from the closest file that sets
+// either channel or channels.
+func (f *File) Destinations(changeType config.ChangeType) []*File {
+	for layer := f; layer != nil; layer = layer.Parent {
+		section := layer.section(changeType)
+		if section.Channel == "" && len(section.Channels) == 0 {
+			continue
 		}
-		return f.Parent.Channel(changeType)
-	case config.ChangeTypePullRequest:
-		if f.PullRequest.Channel != "" {
-			return f.PullRequest.Channel
+		ret := make([]*File, 0, len(section.Channels)+1)
+		if section.Channel != "" {
+			ret = append(ret, f)
 		}
-		return f.Parent.Channel(changeType)
-	default:
-		panic("unknown change type")
+		for idx := range section.Channels {
+			ret = append(ret, section.Channels[idx].asFile(f, changeType))
+		}
+		return ret
+	}
+	return nil
+}
+
+// Ignores returns true if relPath, relative to the directory of the file, matches one of the ignore patterns
+func (f *File) Ignores(relPath string) (bool, error) {
+	if f == nil {
+		return false, nil
+	}
+	return matchesAny(f.Ignore, nil, relPath)
+}
+
+// WithMatchingRules layers every rule that matches relPath on top of the file, in order, so later rules win.
+// It returns the most specific layer, which is the file itself if no rule matches.
+func (f *File) WithMatchingRules(relPath string) (*File, error) {
+	ret := f
+	for idx := range f.Rules {
+		matched, err := f.Rules[idx].Matches(relPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match rule %d against %s: %w", idx, relPath, err)
+		}
+		if matched {
+			ret = f.Rules[idx].asFile(ret, f.ChangedFile)
+		}
+	}
+	return ret, nil
+}
+
+// Dir returns the directory of the notification file, relative to the root of the repository
+func (f *File) Dir() string {
+	if f == nil {
+		return ""
+	}
+	return f.dir
+}
+
+// GeneratedFiles returns how generated files are handled, inheriting from the parent if not set
+func (f *File) GeneratedFiles(changeType config.ChangeType) GeneratedFilesMode {
+	if f == nil {
+		return GeneratedFilesInclude
+	}
+	if mode := f.section(changeType).GeneratedFiles; mode != "" {
+		return mode
 	}
+	return f.Parent.GeneratedFiles(changeType)
 }
 
 func (f *File) String() string {
-	return fmt.Sprintf("File{PullRequest:%v,Commit:%v,PrettyName:%v,MessageTemplate:%v,Parent:%v,ChangedFile:%v}", f.PullRequest, f.Commit, f.PrettyName, f.MessageTemplate, f.Parent, f.ChangedFile)
+	return fmt.Sprintf("File{Sections:%+v,PrettyName:%v,MessageTemplate:%v,Parent:%v,ChangedFile:%v}", f.Sections, f.PrettyName, f.MessageTemplate, f.Parent, f.ChangedFile)
 }
@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriberMergeModes(t *testing.T) {
+	var parent, child File
+	require.NoError(t, decodeStrict("parent", []byte("pullRequest:\n  users: [platform@example.com]\n  groups: [platform]\n"), &parent))
+	require.NoError(t, decodeStrict("child", []byte("pullRequest:\n  users:\n    mode: replace\n    values: [team@example.com]\n  groups: [team]\n"), &child))
+	child.Parent = &parent
+	assert.Equal(t, []string{"team@example.com"}, child.AllUsers(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"team", "platform"}, child.AllGroups(config.ChangeTypePullRequest))
+}
+
+func TestStopsInheritance(t *testing.T) {
+	inherit := false
+	assert.False(t, (&File{}).StopsInheritance())
+	assert.True(t, (&File{Root: true}).StopsInheritance())
+	assert.True(t, (&File{Inherit: &inherit}).StopsInheritance())
+}
+
+func TestLifecycleSections(t *testing.T) {
+	var f File
+	require.NoError(t, decodeStrict("f", []byte("pullRequest:\n  channel: '#all'\nmerged:\n  channel: '#merged'\n"), &f))
+	assert.Equal(t, "#merged", f.Channel(config.PullRequestChangeType("closed", true)))
+	assert.Equal(t, "#all", f.Channel(config.PullRequestChangeType("synchronize", false)))
+	assert.Equal(t, "#all", f.Channel(config.PullRequestChangeType("labeled", false)))
+	assert.Equal(t, "", f.Channel(config.ChangeTypeCommit))
+}
+
+func TestDestinations(t *testing.T) {
+	var parent, child File
+	require.NoError(t, decodeStrict("parent", []byte(`pullRequest:
+  channel: '#team'
+  users: [team@example.com]
+  channels:
+    - channel: '#release-watch'
+      users:
+        mode: replace
+        values: [release@example.com]
+`), &parent))
+	require.NoError(t, decodeStrict("child", []byte("pullRequest:\n  users: [child@example.com]\n"), &child))
+	child.Parent = &parent
+	destinations := child.Destinations(config.ChangeTypePullRequest)
+	require.Len(t, destinations, 2)
+	assert.Equal(t, "#team", destinations[0].Channel(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"child@example.com", "team@example.com"}, destinations[0].AllUsers(config.ChangeTypePullRequest))
+	assert.Equal(t, "#release-watch", destinations[1].Channel(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"release@example.com"}, destinations[1].AllUsers(config.ChangeTypePullRequest))
+	assert.Empty(t, child.Destinations(config.ChangeTypeCommit))
+}
+
+func TestWithConditions(t *testing.T) {
+	var root, release File
+	require.NoError(t, decodeStrict("root", []byte("pullRequest:\n  channel: '#all'\n  when:\n    bots: false\n"), &root))
+	require.NoError(t, decodeStrict("release", []byte("pullRequest:\n  channel: '#release'\n  when:\n    baseBranches: ['release/*']\n"), &release))
+	release.Parent = &root
+	channelFor := func(cc ChangeContext) string {
+		withConditions, err := release.WithConditions(config.ChangeTypePullRequest, cc)
+		require.NoError(t, err)
+		return withConditions.Channel(config.ChangeTypePullRequest)
+	}
+	assert.Equal(t, "#release", channelFor(ChangeContext{BaseBranch: "release/1.2", Author: "someone"}))
+	assert.Equal(t, "#all", channelFor(ChangeContext{BaseBranch: "main", Author: "someone"}))
+	assert.Equal(t, "", channelFor(ChangeContext{BaseBranch: "main", Author: "renovate[bot]"}))
+	assert.Equal(t, "#release", release.Channel(config.ChangeTypePullRequest), "the original file is not modified")
+}
+
+func TestContentMatch(t *testing.T) {
+	var f File
+	require.NoError(t, decodeStrict("f", []byte("pullRequest:\n  channel: '#db'\n  contentMatch: ['(?i)drop table']\n"), &f))
+	patch := "@@ -1,2 +1,2 @@\n-CREATE TABLE a;\n+DROP TABLE a;\n context drop table\n"
+	matched, err := f.WithConditions(config.ChangeTypePullRequest, ChangeContext{Patch: patch})
+	require.NoError(t, err)
+	assert.Equal(t, "#db", matched.Channel(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"+DROP TABLE a;"}, matched.AllMatchedLines(config.ChangeTypePullRequest))
+
+	notMatched, err := f.WithConditions(config.ChangeTypePullRequest, ChangeContext{Patch: "+SELECT 1;\n"})
+	require.NoError(t, err)
+	assert.Equal(t, "", notMatched.Channel(config.ChangeTypePullRequest))
+}
+
+func TestSizeConditions(t *testing.T) {
+	var f File
+	require.NoError(t, decodeStrict("f", []byte("pullRequest:\n  channel: '#svc'\n  when:\n    minLinesChanged: 10\n    maxFiles: 2\n    minPercentFilesChanged: 50\n"), &f))
+	f.dir = "svc"
+	channelFor := func(files ...ghclient.ChangedFile) string {
+		withConditions, err := f.WithConditions(config.ChangeTypePullRequest, ChangeContext{
+			AllFiles: append(files, ghclient.ChangedFile{Filename: "other/a.go", Additions: 100}),
+			CountFiles: func(dir string) (int, error) {
+				assert.Equal(t, "svc", dir)
+				return 3, nil
+			},
+		})
+		require.NoError(t, err)
+		return withConditions.Channel(config.ChangeTypePullRequest)
+	}
+	assert.Equal(t, "#svc", channelFor(ghclient.ChangedFile{Filename: "svc/a.go", Additions: 5}, ghclient.ChangedFile{Filename: "svc/b.go", Deletions: 5}))
+	assert.Equal(t, "", channelFor(ghclient.ChangedFile{Filename: "svc/a.go", Additions: 5}, ghclient.ChangedFile{Filename: "svc/b.go", Deletions: 4}), "too few lines")
+	assert.Equal(t, "", channelFor(ghclient.ChangedFile{Filename: "svc/a.go", Additions: 20}), "too few files in the directory")
+	assert.Equal(t, "", channelFor(ghclient.ChangedFile{Filename: "svc/a.go", Additions: 5}, ghclient.ChangedFile{Filename: "svc/b.go", Deletions: 5}, ghclient.ChangedFile{Filename: "svc/c.go"}), "too many files")
+}
+
+func TestResolveTeams(t *testing.T) {
+	teams := map[string]Team{
+		"team-db": {Users: []string{"db@example.com"}, Groups: []string{"db"}, Channel: "#db"},
+	}
+	var f File
+	require.NoError(t, decodeStrict("f", []byte("pullRequest:\n  team: team-db\n  users: [me@example.com]\n  channels:\n    - team: team-db\n"), &f))
+	require.NoError(t, f.resolveTeams(teams))
+	assert.Equal(t, "#db", f.Channel(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"me@example.com", "db@example.com"}, f.AllUsers(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"db"}, f.AllGroups(config.ChangeTypePullRequest))
+	assert.Equal(t, "#db", f.PullRequest.Channels[0].Channel)
+
+	var unknown File
+	require.NoError(t, decodeStrict("f", []byte("commit:\n  team: team-web\n"), &unknown))
+	assert.ErrorContains(t, unknown.resolveTeams(teams), "commit: unknown team team-web")
+}
+
+func TestProcessTemplate(t *testing.T) {
+	parent := &File{PrettyName: []string{"Platform"}, MessageTemplate: `{{ .PrettyName | join ", " }}`}
+	child := &File{
+		PrettyName:      []string{"DB", "Schemas"},
+		MessageTemplate: `{{ .PrettyName | join "/" }}: {{ truncate 10 .Title }} by {{ .Author }}, {{ pluralize (len .Files) "file" "files" }} +{{ .Additions }} on {{ regexReplace "^feature/" "" .HeadBranch }}`,
+		Parent:          parent,
+	}
+	data := TemplateContext{Title: "Add an index to users", Author: "someone", HeadBranch: "feature/index"}.WithFiles([]ghclient.ChangedFile{
+		{Filename: "db/a.sql", Additions: 3},
+		{Filename: "db/b.sql", Additions: 4},
+	})
+	msg, err := child.ProcessTemplate(config.ChangeTypePullRequest, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Platform - DB/Schemas: Add an in… by someone, 2 files +7 on index", msg)
+}
+
+func TestProcessTemplateMessageMode(t *testing.T) {
+	run := func(mode MessageMode, childTemplate string, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			parent := &File{MessageTemplate: "parent"}
+			child := &File{MessageTemplate: childTemplate, MessageMode: mode, Parent: parent}
+			msg, err := child.ProcessTemplate(config.ChangeTypePullRequest, TemplateContext{})
+			require.NoError(t, err)
+			assert.Equal(t, expected, msg)
+		}
+	}
+	t.Run("append", run(MessageModeAppend, "child", "parent - child"))
+	t.Run("default", run("", "child", "parent - child"))
+	t.Run("prepend", run(MessageModePrepend, "child", "child - parent"))
+	t.Run("replace", run(MessageModeReplace, "child", "child"))
+	t.Run("section", run(MessageModeSection, "child", "parent\nchild"))
+	t.Run("parent message", run(MessageModeReplace, "child ({{ .ParentMessage }})", "child (parent)"))
+	t.Run("replace without template", run(MessageModeReplace, "", ""))
+	t.Run("append without template", run(MessageModeAppend, "", "parent"))
+}
+
+func TestProcessTemplateFileFields(t *testing.T) {
+	var f File
+	require.NoError(t, decodeStrict("f", []byte("pullRequest:\n  channel: '#db'\n  messageTemplate: '{{ .ChangedFile }} changed, see {{ .PullRequest.Channel }} or {{ .Commit.Channel }}{{ .MessageTemplate }} ({{ .Title }})'\n"), &f))
+	f.ChangedFile = "db/schema.sql"
+	msg, err := f.ProcessTemplate(config.ChangeTypePullRequest, TemplateContext{Title: "Add an index"})
+	require.NoError(t, err)
+	assert.Equal(t, "db/schema.sql changed, see #db or  (Add an index)", msg)
+}
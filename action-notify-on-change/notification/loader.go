@@ -4,34 +4,197 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
-
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/localrepo"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	ignore "github.com/sabhiram/go-gitignore"
 	"gopkg.in/yaml.v2"
 )
 
 const notificationFile = ".action-notify-on-change.yaml"
 
+// ignoreFile lists, with gitignore semantics, files that never trigger notifications
+const ignoreFile = ".notifyignore"
+
+// IsConfigFile returns true if path is one of the files that configure who is notified
+func IsConfigFile(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	return filepath.Base(path) == notificationFile || path == routingFile || path == ignoreFile
+}
+
+// Repository reads the files of the repository at the commit being notified about
+type Repository interface {
+	// GetContents returns the content of the file at path, or nil if there is no such file
+	GetContents(ctx context.Context, path string) ([]byte, error)
+	// CountFiles returns how many files are under dir
+	CountFiles(ctx context.Context, dir string) (int, error)
+}
+
+var (
+	_ Repository = (*ghclient.GhClient)(nil)
+	_ Repository = (*localrepo.Repo)(nil)
+)
+
 type Loader struct {
-	ghClient *ghclient.GhClient
+	cfg        config.Config
+	ghClient   *ghclient.GhClient
+	localRepo  *localrepo.Repo
+	repository Repository
+	mode       config.LoaderMode
+	logger     logger.Logger
+	// contents caches the files read from the repository, as many directories share ancestors and included files
+	contents onceCache[[]byte]
+
+	treeOnce sync.Once
+	tree     *treeContents
+	treeErr  error
 }
 
-func NewLoader(ghClient *ghclient.GhClient) *Loader {
-	return &Loader{
-		ghClient: ghClient,
+func NewLoader(cfg config.Config, ghClient *ghclient.GhClient, localRepo *localrepo.Repo, logger logger.Logger) *Loader {
+	if sha := cfg.ConfigSha(); sha != cfg.CommitSha {
+		logger.Infof("reading notification files at %s", sha)
+		ghClient = ghClient.At(sha)
+		localRepo = localRepo.At(sha)
+	}
+	ret := &Loader{
+		cfg:        cfg,
+		ghClient:   ghClient,
+		localRepo:  localRepo,
+		repository: ghClient,
+		mode:       cfg.LoaderMode,
+		logger:     logger,
+	}
+	if cfg.Source == config.SourceLocal {
+		// The checkout already has every file: listing them first would not save anything
+		ret.repository = localRepo
+		ret.mode = config.LoaderModeContents
 	}
+	return ret
+}
+
+// At returns a loader that reads notification files at sha
+func (n *Loader) At(sha string) *Loader {
+	cfg := n.cfg
+	cfg.CommitSha = sha
+	cfg.ConfigRef = config.ConfigRefHead
+	return NewLoader(cfg, n.ghClient.At(sha), n.localRepo.At(sha), n.logger)
 }
 
 func (n *Loader) LoadForPath(ctx context.Context, path string) (*File, error) {
 	filePath := filepath.Join(path, notificationFile)
-	// Note: If we get throttled here, we can cache results
-	fileContent, err := n.ghClient.GetContents(ctx, filePath)
+	fileContent, err := n.GetContents(ctx, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get contents for %s: %w", path, err)
 	}
 	var ret File
-	if err := yaml.Unmarshal(fileContent, &ret); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal file %s as yaml: %w", filePath, err)
+	if err := n.decodeWithIncludes(ctx, filePath, fileContent, &ret, nil); err != nil {
+		return nil, err
+	}
+	if err := ret.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid notification file %s: %w", filePath, err)
+	}
+	return &ret, nil
+}
+
+// GetContents returns the content of the file at path, reading it from the repository only once per run
+func (n *Loader) GetContents(ctx context.Context, path string) ([]byte, error) {
+	return n.contents.get(path, func() ([]byte, error) {
+		if n.mode == config.LoaderModeTree {
+			tree, err := n.loadTree(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if tree != nil {
+				return tree.getContents(ctx, path)
+			}
+		}
+		return n.repository.GetContents(ctx, path)
+	})
+}
+
+// CountFiles returns how many files of the repository are under dir
+func (n *Loader) CountFiles(ctx context.Context, dir string) (int, error) {
+	return n.repository.CountFiles(ctx, dir)
+}
+
+// BaseSha returns the commit the pull request or commit is compared with, or "" for the first commit of a repository
+func (n *Loader) BaseSha(ctx context.Context) (string, error) {
+	if n.cfg.Source == config.SourceLocal {
+		return n.localRepo.BaseSha(ctx)
+	}
+	return n.ghClient.BaseSha(ctx)
+}
+
+// maxIncludeDepth stops include cycles
+const maxIncludeDepth = 10
+
+// decodeWithIncludes decodes the files content includes into ret, in order, and then content itself so its values
+// win. includedBy is the chain of files that included filePath.
+func (n *Loader) decodeWithIncludes(ctx context.Context, filePath string, content []byte, ret *File, includedBy []string) error {
+	var includes struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(content, &includes); err != nil {
+		// Let the strict decoding below report where the error is
+		includes.Include = nil
+	}
+	includedBy = append(includedBy, filePath)
+	if len(includes.Include) > 0 && len(includedBy) > maxIncludeDepth {
+		return fmt.Errorf("too many nested includes: %v", includedBy)
+	}
+	for _, include := range includes.Include {
+		includePath := filepath.Clean(strings.TrimPrefix(include, "/"))
+		if !localrepo.IsInside(includePath) {
+			return fmt.Errorf("file %s included by %s is outside of the repository", include, filePath)
+		}
+		includeContent, err := n.GetContents(ctx, includePath)
+		if err != nil {
+			return fmt.Errorf("failed to get contents for %s included by %s: %w", includePath, filePath, err)
+		}
+		if includeContent == nil {
+			return fmt.Errorf("file %s included by %s does not exist", includePath, filePath)
+		}
+		if err := n.decodeWithIncludes(ctx, includePath, includeContent, ret, includedBy); err != nil {
+			return err
+		}
+	}
+	if err := decodeStrict(filePath, content, ret); err != nil {
+		return fmt.Errorf("failed to unmarshal file %s as yaml: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadRoutingFile loads the central routing file, returning nil if the repository does not have one
+func (n *Loader) LoadRoutingFile(ctx context.Context) (*RoutingFile, error) {
+	fileContent, err := n.GetContents(ctx, routingFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contents for %s: %w", routingFile, err)
+	}
+	if fileContent == nil {
+		return nil, nil
+	}
+	var ret RoutingFile
+	if err := decodeStrict(routingFile, fileContent, &ret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file %s as yaml: %w", routingFile, err)
+	}
+	if err := ret.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid routing file %s: %w", routingFile, err)
 	}
 	return &ret, nil
 }
+
+// LoadIgnoreFile loads the .notifyignore file at the root of the repository, returning nil if there is none
+func (n *Loader) LoadIgnoreFile(ctx context.Context) (*ignore.GitIgnore, error) {
+	fileContent, err := n.GetContents(ctx, ignoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contents for %s: %w", ignoreFile, err)
+	}
+	if fileContent == nil {
+		return nil, nil
+	}
+	return ignore.CompileIgnoreLines(strings.Split(string(fileContent), "\n")...), nil
+}
@@ -0,0 +1,16 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadForPathIncludeOutsideRepository(t *testing.T) {
+	loader := newTestMerger(t, fakeRepository{
+		".action-notify-on-change.yaml": "include: ['../../../etc/passwd']\n",
+	}).NotificationLoader
+	_, err := loader.LoadForPath(context.Background(), ".")
+	assert.ErrorContains(t, err, "file ../../../etc/passwd included by .action-notify-on-change.yaml is outside of the repository")
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+)
+
+type MessageFormat string
+
+const (
+	// MessageFormatMrkdwn sends the output of the template to Slack as is
+	MessageFormatMrkdwn MessageFormat = "mrkdwn"
+	// MessageFormatMarkdown converts GitHub flavored Markdown in the output of the template to Slack mrkdwn
+	MessageFormatMarkdown MessageFormat = "markdown"
+)
+
+var (
+	markdownHeading  = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	markdownListItem = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownBold     = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownItalic   = regexp.MustCompile(`\*([^*\s][^*]*?)\*`)
+	markdownStrike   = regexp.MustCompile(`~~(.+?)~~`)
+	markdownCodeSpan = regexp.MustCompile("`[^`]*`")
+)
+
+const (
+	codeFence       = "```"
+	boldPlaceholder = "\x00"
+)
+
+// markdownToMrkdwn converts the GitHub flavored Markdown Slack does not understand, like **bold**, [links](url),
+// headings and lists, to Slack mrkdwn. Code blocks and code spans are left alone.
+func markdownToMrkdwn(s string) string {
+	lines := strings.Split(s, "\n")
+	inCodeBlock := false
+	for idx, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), codeFence) {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+		if matches := markdownHeading.FindStringSubmatch(line); matches != nil {
+			line = "**" + matches[1] + "**"
+		}
+		if matches := markdownListItem.FindStringSubmatch(line); matches != nil {
+			line = matches[1] + "• " + matches[2]
+		}
+		lines[idx] = convertInline(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertInline converts the inline Markdown of line, outside of code spans
+func convertInline(line string) string {
+	codeSpans := markdownCodeSpan.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	start := 0
+	for _, span := range codeSpans {
+		b.WriteString(convertText(line[start:span[0]]))
+		b.WriteString(line[span[0]:span[1]])
+		start = span[1]
+	}
+	b.WriteString(convertText(line[start:]))
+	return b.String()
+}
+
+func convertText(text string) string {
+	text = markdownLink.ReplaceAllString(text, "<$2|$1>")
+	// Bold uses a placeholder so the italic conversion below does not see it
+	text = markdownBold.ReplaceAllString(text, boldPlaceholder+"$1$2"+boldPlaceholder)
+	text = markdownItalic.ReplaceAllString(text, "_${1}_")
+	text = markdownStrike.ReplaceAllString(text, "~$1~")
+	return strings.ReplaceAll(text, boldPlaceholder, "*")
+}
@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToMrkdwn(t *testing.T) {
+	run := func(input string, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			assert.Equal(t, expected, markdownToMrkdwn(input))
+		}
+	}
+	t.Run("plain", run("nothing to do", "nothing to do"))
+	t.Run("bold", run("a **bold** and __bold__ word", "a *bold* and *bold* word"))
+	t.Run("italic", run("an *italic* word", "an _italic_ word"))
+	t.Run("bold and italic", run("**bold** *italic*", "*bold* _italic_"))
+	t.Run("strike", run("~~gone~~", "~gone~"))
+	t.Run("link", run("see [the PR](https://example.com/1)", "see <https://example.com/1|the PR>"))
+	t.Run("heading", run("## Owners", "*Owners*"))
+	t.Run("list", run("- one\n  * two", "• one\n  • two"))
+	t.Run("code span", run("`**not bold**` **bold**", "`**not bold**` *bold*"))
+	t.Run("code block", run("```\n**not bold**\n```\n**bold**", "```\n**not bold**\n```\n*bold*"))
+}
+
+func TestTemplateEscaping(t *testing.T) {
+	data := TemplateContext{Title: "Fix <!here> & <b>", Link: "https://example.com/1"}
+	msg, err := executeTemplate(`{{ .Title | slackEscape }} {{ mrkdwnLink .Link .Title }} & "quoted"`, MessageFormatMrkdwn, data)
+	assert.NoError(t, err)
+	assert.Equal(t, `Fix &lt;!here&gt; &amp; &lt;b&gt; <https://example.com/1|Fix &lt;!here&gt; &amp; &lt;b&gt;> & "quoted"`, msg)
+}
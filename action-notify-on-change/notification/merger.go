@@ -8,16 +8,32 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
 	"golang.org/x/sync/errgroup"
 )
 
 type Merger struct {
 	NotificationLoader *Loader
+	// concurrency is how many notification files are loaded at once
+	concurrency int
+
+	routingFileOnce sync.Once
+	routingFile     *RoutingFile
+	routingFileErr  error
+
+	teamsOnce sync.Once
+	teams     map[string]Team
+	teamsErr  error
+
+	// dirs caches the notification file of each directory, with its teams resolved. They are shared by every
+	// changed file under the directory, so Merge only modifies copies of them.
+	dirs onceCache[*File]
 }
 
-func NewMerger(notificationLoader *Loader) *Merger {
+func NewMerger(cfg config.Config, notificationLoader *Loader) *Merger {
 	return &Merger{
 		NotificationLoader: notificationLoader,
+		concurrency:        cfg.ConcurrencyLimit(),
 	}
 }
 
@@ -29,49 +45,163 @@ func containsStopFile(path string) bool {
 	return err == nil
 }
 
+func (n *Merger) loadRoutingFile(ctx context.Context) (*RoutingFile, error) {
+	n.routingFileOnce.Do(func() {
+		routes, err := n.NotificationLoader.LoadRoutingFile(ctx)
+		if err != nil {
+			n.routingFileErr = err
+			return
+		}
+		teams, err := n.loadTeams(ctx)
+		if err != nil {
+			n.routingFileErr = fmt.Errorf("failed to load teams: %w", err)
+			return
+		}
+		if err := routes.resolveTeams(teams); err != nil {
+			n.routingFileErr = fmt.Errorf("failed to resolve teams for %s: %w", routingFile, err)
+			return
+		}
+		n.routingFile = routes
+	})
+	return n.routingFile, n.routingFileErr
+}
+
+// loadTeams loads the teams defined in the notification file at the root of the repository
+func (n *Merger) loadTeams(ctx context.Context) (map[string]Team, error) {
+	n.teamsOnce.Do(func() {
+		root, err := n.NotificationLoader.LoadForPath(ctx, ".")
+		if err != nil {
+			n.teamsErr = fmt.Errorf("failed to load root notification file: %w", err)
+			return
+		}
+		n.teams = root.Teams
+	})
+	return n.teams, n.teamsErr
+}
+
+// loadDir returns the notification file of dir, loading it and resolving its teams once per run
+func (n *Merger) loadDir(ctx context.Context, dir string) (*File, error) {
+	return n.dirs.get(dir, func() (*File, error) {
+		notification, err := n.NotificationLoader.LoadForPath(ctx, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load notification for path %s: %w", dir, err)
+		}
+		if notification == nil {
+			return nil, nil
+		}
+		teams, err := n.loadTeams(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load teams: %w", err)
+		}
+		if err := notification.resolveTeams(teams); err != nil {
+			return nil, fmt.Errorf("failed to resolve teams for path %s: %w", dir, err)
+		}
+		return notification, nil
+	})
+}
+
+// Preload loads the notification files of every directory containing one of paths, each directory once, so that
+// merging the notifications of many files under the same directories does not read them again
+func (n *Merger) Preload(ctx context.Context, paths []string) error {
+	dirs := make(map[string]struct{})
+	for _, path := range paths {
+		for path = filepath.Dir(filepath.Clean(path)); ; path = filepath.Dir(path) {
+			if _, exists := dirs[path]; exists {
+				break
+			}
+			dirs[path] = struct{}{}
+			if containsStopFile(path) || filepath.Dir(path) == path {
+				break
+			}
+		}
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(n.concurrency)
+	for dir := range dirs {
+		dir := dir
+		eg.Go(func() error {
+			_, err := n.loadDir(egCtx, dir)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return fmt.Errorf("failed to preload notifications: %w", err)
+	}
+	return nil
+}
+
 func (n *Merger) Merge(ctx context.Context, path string) (*File, error) {
-	// Walk up the path, looking for notification files
+	// Walk up the directories of the path, looking for notification files
 	// Merge them together
 	// Return the merged notification file
-	path = filepath.Clean(path)
-	rootPath := path
+	rootPath := filepath.Clean(path)
+	path = filepath.Dir(rootPath)
 	type loadRetVal struct {
 		idx          int
 		notification *File
+		// mostSpecific is notification with the rules that match the changed file layered on top
+		mostSpecific *File
+		ignored      bool
 	}
 	var i int
 	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(n.concurrency)
 	allRetValues := make([]loadRetVal, 0, 10)
 	var allRetValuesMu sync.Mutex
 	for i = 0; ; i++ {
 		idx := i
 		loadPath := path
+		isRoot := containsStopFile(path) || filepath.Dir(path) == path
 		eg.Go(func() error {
-			notification, err := n.NotificationLoader.LoadForPath(egCtx, loadPath)
+			notification, err := n.loadDir(egCtx, loadPath)
 			if err != nil {
-				return fmt.Errorf("failed to load notification for path %s: %w", loadPath, err)
+				return err
 			}
+			mostSpecific := notification
+			var ignored bool
 			if notification != nil {
+				// Copy the cached file, as it is shared with the other files under loadPath
+				notificationCopy := *notification
+				notification = &notificationCopy
 				notification.ChangedFile = rootPath
+				notification.dir = loadPath
+				relPath, err := filepath.Rel(loadPath, rootPath)
+				if err != nil {
+					return fmt.Errorf("failed to find relative path of %s in %s: %w", rootPath, loadPath, err)
+				}
+				ignored, err = notification.Ignores(filepath.ToSlash(relPath))
+				if err != nil {
+					return fmt.Errorf("failed to check ignore patterns for path %s: %w", loadPath, err)
+				}
+				mostSpecific, err = notification.WithMatchingRules(filepath.ToSlash(relPath))
+				if err != nil {
+					return fmt.Errorf("failed to apply rules for path %s: %w", loadPath, err)
+				}
+				if isRoot {
+					routes, err := n.loadRoutingFile(egCtx)
+					if err != nil {
+						return fmt.Errorf("failed to load routing file: %w", err)
+					}
+					mostSpecific, err = routes.WithMatchingRoutes(mostSpecific, filepath.ToSlash(rootPath))
+					if err != nil {
+						return fmt.Errorf("failed to apply routes for path %s: %w", rootPath, err)
+					}
+				}
 			}
 			allRetValuesMu.Lock()
 			defer allRetValuesMu.Unlock()
 			allRetValues = append(allRetValues, loadRetVal{
 				idx:          idx,
 				notification: notification,
+				mostSpecific: mostSpecific,
+				ignored:      ignored,
 			})
 			return nil
 		})
-		if containsStopFile(path) {
+		if isRoot {
 			break
 		}
-		newPath := filepath.Dir(path)
-
-		if newPath == path {
-			// We've reached the root
-			break
-		}
-		path = newPath
+		path = filepath.Dir(path)
 	}
 	if err := eg.Wait(); err != nil {
 		return nil, fmt.Errorf("failed to load notifications: %w", err)
@@ -79,9 +209,16 @@ func (n *Merger) Merge(ctx context.Context, path string) (*File, error) {
 	sort.Slice(allRetValues, func(i, j int) bool {
 		return allRetValues[i].idx < allRetValues[j].idx
 	})
-	ret := allRetValues[0].notification
-	for idx := 1; idx < len(allRetValues); idx++ {
-		allRetValues[idx-1].notification.Parent = allRetValues[idx].notification
+	ret := allRetValues[0].mostSpecific
+	for idx := 0; idx < len(allRetValues); idx++ {
+		if allRetValues[idx].ignored {
+			// An ignored file does not trigger any notification
+			return nil, nil
+		}
+		if idx+1 == len(allRetValues) || allRetValues[idx].notification.StopsInheritance() {
+			break
+		}
+		allRetValues[idx].notification.Parent = allRetValues[idx+1].mostSpecific
 	}
 	return ret, nil
 }
@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is a repository whose files are the keys of the map
+type fakeRepository map[string]string
+
+func (r fakeRepository) GetContents(_ context.Context, path string) ([]byte, error) {
+	content, exists := r[filepath.ToSlash(path)]
+	if !exists {
+		return nil, nil
+	}
+	return []byte(content), nil
+}
+
+func (r fakeRepository) CountFiles(_ context.Context, dir string) (int, error) {
+	var ret int
+	for path := range r {
+		if dir == "." || strings.HasPrefix(path, dir+"/") {
+			ret++
+		}
+	}
+	return ret, nil
+}
+
+// recordingRepository records the paths read from the repository
+type recordingRepository struct {
+	fakeRepository
+	mu    sync.Mutex
+	reads []string
+}
+
+func (r *recordingRepository) GetContents(ctx context.Context, path string) ([]byte, error) {
+	r.mu.Lock()
+	r.reads = append(r.reads, filepath.ToSlash(path))
+	r.mu.Unlock()
+	return r.fakeRepository.GetContents(ctx, path)
+}
+
+func newTestMerger(t *testing.T, files Repository) *Merger {
+	return NewMerger(config.Config{}, &Loader{repository: files, logger: logger.NewTestLogger(t)})
+}
+
+func TestMergeIgnore(t *testing.T) {
+	merger := newTestMerger(t, fakeRepository{
+		".action-notify-on-change.yaml":    "ignore: ['**/*.md']\npullRequest:\n  channel: '#all'\n",
+		"db/.action-notify-on-change.yaml": "ignore: ['testdata/**']\npullRequest:\n  channel: '#db'\n",
+	})
+	channelFor := func(path string) string {
+		merged, err := merger.Merge(context.Background(), path)
+		require.NoError(t, err)
+		if merged == nil {
+			return "ignored"
+		}
+		return merged.Channel(config.ChangeTypePullRequest)
+	}
+	assert.Equal(t, "#db", channelFor("db/schema.sql"))
+	assert.Equal(t, "ignored", channelFor("db/testdata/schema.sql"))
+	assert.Equal(t, "ignored", channelFor("db/README.md"), "ignored by a parent directory")
+	assert.Equal(t, "#all", channelFor("testdata/schema.sql"), "only ignored under db")
+}
+
+func TestPreload(t *testing.T) {
+	repository := &recordingRepository{fakeRepository: fakeRepository{
+		".action-notify-on-change.yaml":    "pullRequest:\n  channel: '#all'\n",
+		"db/.action-notify-on-change.yaml": "pullRequest:\n  channel: '#db'\n",
+	}}
+	merger := newTestMerger(t, repository)
+	require.NoError(t, merger.Preload(context.Background(), []string{"db/a.sql", "db/b.sql", "db/migrations/001.sql", "main.go"}))
+	merged, err := merger.Merge(context.Background(), "db/migrations/001.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "#db", merged.Channel(config.ChangeTypePullRequest))
+	sort.Strings(repository.reads)
+	assert.Equal(t, []string{
+		".action-notify-on-change.yaml",
+		".github/notify-on-change.yaml",
+		"db/.action-notify-on-change.yaml",
+		"db/migrations/.action-notify-on-change.yaml",
+	}, repository.reads, "each directory is read once, and changed files are not read as directories")
+}
@@ -0,0 +1,55 @@
+package notification
+
+import (
+	"fmt"
+)
+
+// routingFile is a single file at the root of the repository that routes changes by path, similar to CODEOWNERS
+const routingFile = ".github/notify-on-change.yaml"
+
+type RoutingMode string
+
+const (
+	// RoutingModeLastMatch only uses the last route that matches a changed file, like CODEOWNERS
+	RoutingModeLastMatch RoutingMode = "lastMatch"
+	// RoutingModeMerge uses every route that matches a changed file, with later routes taking priority
+	RoutingModeMerge RoutingMode = "merge"
+)
+
+// RoutingFile maps ordered path patterns, relative to the repository root, to notifications
+type RoutingFile struct {
+	Mode   RoutingMode `yaml:"mode,omitempty"`
+	Routes []Rule      `yaml:"routes,omitempty"`
+}
+
+// WithMatchingRoutes layers the routes that match relPath on top of root, which is the notification file at the
+// root of the repository.
+func (r *RoutingFile) WithMatchingRoutes(root *File, relPath string) (*File, error) {
+	if r == nil {
+		return root, nil
+	}
+	matching := make([]*Rule, 0, len(r.Routes))
+	for idx := range r.Routes {
+		matched, err := r.Routes[idx].Matches(relPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match route %d against %s: %w", idx, relPath, err)
+		}
+		if matched {
+			matching = append(matching, &r.Routes[idx])
+		}
+	}
+	switch r.Mode {
+	case RoutingModeLastMatch, "":
+		if len(matching) > 1 {
+			matching = matching[len(matching)-1:]
+		}
+	case RoutingModeMerge:
+	default:
+		return nil, fmt.Errorf("unknown routing mode %s", r.Mode)
+	}
+	ret := root
+	for _, route := range matching {
+		ret = route.asFile(ret, root.ChangedFile)
+	}
+	return ret, nil
+}
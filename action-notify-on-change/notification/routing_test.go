@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWithRoutingFile(t *testing.T) {
+	const root = `teams:
+  team-db:
+    users: [db@example.com]
+    channel: '#db'
+pullRequest:
+  channel: '#all'
+  users: [root@example.com]
+`
+	const routes = `mode: %s
+routes:
+  - paths: ['**/*.sql']
+    pullRequest:
+      team: team-db
+  - paths: ['db/migrations/**']
+    pullRequest:
+      channel: '#migrations'
+`
+	run := func(mode RoutingMode, path string, expectedChannel string, expectedUsers []string) func(t *testing.T) {
+		return func(t *testing.T) {
+			merger := newTestMerger(t, fakeRepository{
+				".action-notify-on-change.yaml":    root,
+				".github/notify-on-change.yaml":    fmt.Sprintf(routes, mode),
+				"db/.action-notify-on-change.yaml": "pullRequest:\n  users: [dba@example.com]\n",
+			})
+			merged, err := merger.Merge(context.Background(), path)
+			require.NoError(t, err)
+			assert.Equal(t, expectedChannel, merged.Channel(config.ChangeTypePullRequest))
+			assert.Equal(t, expectedUsers, merged.AllUsers(config.ChangeTypePullRequest))
+		}
+	}
+	t.Run("no route", run(RoutingModeLastMatch, "api/main.go", "#all", []string{"root@example.com"}))
+	t.Run("team route", run(RoutingModeLastMatch, "schema.sql", "#db", []string{"db@example.com", "root@example.com"}))
+	t.Run("last match", run(RoutingModeLastMatch, "db/migrations/001.sql", "#migrations", []string{"dba@example.com", "root@example.com"}))
+	t.Run("merge", run(RoutingModeMerge, "db/migrations/001.sql", "#migrations", []string{"dba@example.com", "db@example.com", "root@example.com"}))
+	t.Run("below a directory file", run(RoutingModeLastMatch, "db/schema.sql", "#db", []string{"dba@example.com", "db@example.com", "root@example.com"}))
+}
+
+func TestMergeWithRoutingFileUnknownTeam(t *testing.T) {
+	merger := newTestMerger(t, fakeRepository{
+		".github/notify-on-change.yaml": "routes:\n  - paths: ['**']\n    pullRequest:\n      team: team-web\n",
+	})
+	_, err := merger.Merge(context.Background(), "main.go")
+	assert.ErrorContains(t, err, "routes[0]: pullRequest: unknown team team-web")
+}
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Rule narrows a notification to only some of the files under the directory of the notification file.
+// Paths are matched relative to the directory that contains the notification file.
+type Rule struct {
+	// Glob patterns (for example **/*.sql or migrations/**) of files this rule applies to
+	Paths []string `yaml:"paths,omitempty"`
+	// Glob patterns of files this rule never applies to, even if they match Paths
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Regular expressions of files this rule applies to
+	PathRegex []string `yaml:"pathRegex,omitempty"`
+	// Regular expressions of files this rule never applies to
+	ExcludeRegex    []string `yaml:"excludeRegex,omitempty"`
+	Sections        `yaml:",inline"`
+	PrettyName      []string `yaml:"prettyName,omitempty"`
+	MessageTemplate string   `yaml:"messageTemplate,omitempty"`
+	// Format of the output of MessageTemplate: mrkdwn, the default, or markdown
+	Format MessageFormat `yaml:"format,omitempty"`
+}
+
+// Matches returns true if the rule applies to relPath, a slash separated path relative to the notification file.
+func (r *Rule) Matches(relPath string) (bool, error) {
+	included, err := matchesAny(r.Paths, r.PathRegex, relPath)
+	if err != nil {
+		return false, err
+	}
+	if !included {
+		return false, nil
+	}
+	excluded, err := matchesAny(r.Exclude, r.ExcludeRegex, relPath)
+	if err != nil {
+		return false, err
+	}
+	return !excluded, nil
+}
+
+func matchesAny(globs []string, regexes []string, relPath string) (bool, error) {
+	for _, glob := range globs {
+		matched, err := doublestar.Match(glob, relPath)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob %s: %w", glob, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	for _, rgx := range regexes {
+		compiled, err := regexp.Compile(rgx)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %s: %w", rgx, err)
+		}
+		if compiled.MatchString(relPath) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// asFile turns the rule into a notification file so it can be layered on top of the file that defines it
+func (r *Rule) asFile(parent *File, changedFile string) *File {
+	prettyName := r.PrettyName
+	if len(prettyName) == 0 {
+		prettyName = parent.PrettyName
+	}
+	return &File{
+		Sections:        r.Sections,
+		PrettyName:      prettyName,
+		MessageTemplate: r.MessageTemplate,
+		Format:          r.Format,
+		Parent:          parent,
+		ChangedFile:     changedFile,
+		dir:             parent.dir,
+		rule:            r,
+	}
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleMatches(t *testing.T) {
+	run := func(rule Rule, relPath string, expected bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			actual, err := rule.Matches(relPath)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		}
+	}
+	sqlRule := Rule{Paths: []string{"**/*.sql"}, Exclude: []string{"testdata/**"}}
+	t.Run("no paths", run(Rule{}, "a.go", false))
+	t.Run("glob at root", run(sqlRule, "schema.sql", true))
+	t.Run("glob nested", run(sqlRule, "db/schema.sql", true))
+	t.Run("glob miss", run(sqlRule, "db/schema.go", false))
+	t.Run("excluded", run(sqlRule, "testdata/schema.sql", false))
+	t.Run("regex", run(Rule{PathRegex: []string{`^migrations/[0-9]+_`}}, "migrations/001_init.sql", true))
+	t.Run("exclude regex", run(Rule{Paths: []string{"**"}, ExcludeRegex: []string{`_test\.go$`}}, "a_test.go", false))
+}
+
+func TestWithMatchingRules(t *testing.T) {
+	f := &File{
+		Sections: Sections{PullRequest: Notification{Channel: "#service"}},
+		Rules: []Rule{
+			{Paths: []string{"**/*.sql"}, Sections: Sections{PullRequest: Notification{Channel: "#db", Users: Subscribers{Values: []string{"db@example.com"}}}}},
+		},
+	}
+	sqlFile, err := f.WithMatchingRules("schema.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "#db", sqlFile.Channel(config.ChangeTypePullRequest))
+	assert.Equal(t, []string{"db@example.com"}, sqlFile.AllUsers(config.ChangeTypePullRequest))
+
+	goFile, err := f.WithMatchingRules("main.go")
+	require.NoError(t, err)
+	assert.Same(t, f, goFile)
+	assert.Equal(t, "#service", goFile.Channel(config.ChangeTypePullRequest))
+}
@@ -0,0 +1,8 @@
+package notification
+
+import _ "embed"
+
+// JSONSchema is the published JSON Schema of notification files, for editors and CI validation
+//
+//go:embed schema.json
+var JSONSchema []byte
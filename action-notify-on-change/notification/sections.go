@@ -0,0 +1,88 @@
+package notification
+
+import "github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+
+// Sections are the notifications for each kind of change. The pull request lifecycle sections, when set, replace
+// the pullRequest section for that event.
+type Sections struct {
+	PullRequest     Notification  `yaml:"pullRequest,omitempty"`
+	Commit          Notification  `yaml:"commit,omitempty"`
+	Opened          *Notification `yaml:"opened,omitempty"`
+	ReadyForReview  *Notification `yaml:"readyForReview,omitempty"`
+	Synchronize     *Notification `yaml:"synchronize,omitempty"`
+	Merged          *Notification `yaml:"merged,omitempty"`
+	Closed          *Notification `yaml:"closed,omitempty"`
+	Reopened        *Notification `yaml:"reopened,omitempty"`
+	ReviewRequested *Notification `yaml:"reviewRequested,omitempty"`
+}
+
+// ForChangeType returns the section that applies to changeType
+func (s *Sections) ForChangeType(changeType config.ChangeType) *Notification {
+	var lifecycle *Notification
+	switch changeType {
+	case config.ChangeTypeCommit:
+		return &s.Commit
+	case config.ChangeTypePullRequest:
+	case config.ChangeTypePullRequestOpened:
+		lifecycle = s.Opened
+	case config.ChangeTypePullRequestReadyForReview:
+		lifecycle = s.ReadyForReview
+	case config.ChangeTypePullRequestSynchronize:
+		lifecycle = s.Synchronize
+	case config.ChangeTypePullRequestMerged:
+		lifecycle = s.Merged
+	case config.ChangeTypePullRequestClosed:
+		lifecycle = s.Closed
+	case config.ChangeTypePullRequestReopened:
+		lifecycle = s.Reopened
+	case config.ChangeTypePullRequestReviewRequested:
+		lifecycle = s.ReviewRequested
+	default:
+		panic("unknown change type")
+	}
+	if lifecycle != nil {
+		return lifecycle
+	}
+	return &s.PullRequest
+}
+
+// namedSection is a section of a notification file and its yaml key
+type namedSection struct {
+	name         string
+	notification *Notification
+}
+
+// all returns every section that is set
+func (s *Sections) all() []namedSection {
+	ret := []namedSection{
+		{name: "pullRequest", notification: &s.PullRequest},
+		{name: "commit", notification: &s.Commit},
+	}
+	lifecycle := []namedSection{
+		{name: "opened", notification: s.Opened},
+		{name: "readyForReview", notification: s.ReadyForReview},
+		{name: "synchronize", notification: s.Synchronize},
+		{name: "merged", notification: s.Merged},
+		{name: "closed", notification: s.Closed},
+		{name: "reopened", notification: s.Reopened},
+		{name: "reviewRequested", notification: s.ReviewRequested},
+	}
+	for _, section := range lifecycle {
+		if section.notification != nil {
+			ret = append(ret, section)
+		}
+	}
+	return ret
+}
+
+func (s *Sections) validate(field string) []error {
+	prefix := ""
+	if field != "" {
+		prefix = field + "."
+	}
+	var errs []error
+	for _, section := range s.all() {
+		errs = append(errs, section.notification.validate(prefix+section.name)...)
+	}
+	return errs
+}
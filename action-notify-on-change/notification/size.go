@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// hasSizeConditions returns true if the conditions depend on every file the notification applies to, not only the
+// changed file
+func (w *When) hasSizeConditions() bool {
+	return w != nil && (w.MinLinesChanged > 0 || w.MaxFiles > 0 || w.MinPercentFilesChanged > 0)
+}
+
+// matchesSize evaluates the size conditions of w against the changed files this layer applies to: the files under
+// its directory that also match its rule, if it comes from one
+func (f *File) matchesSize(w *When, cc ChangeContext) (bool, error) {
+	var files, linesChanged, filesInDir int
+	for _, changed := range cc.AllFiles {
+		relPath, inDir := relativeTo(f.dir, changed.Filename)
+		if !inDir {
+			continue
+		}
+		filesInDir++
+		if f.rule != nil {
+			matched, err := f.rule.Matches(relPath)
+			if err != nil {
+				return false, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		files++
+		linesChanged += changed.Additions + changed.Deletions
+	}
+	if w.MinLinesChanged > 0 && linesChanged < w.MinLinesChanged {
+		return false, nil
+	}
+	if w.MaxFiles > 0 && files > w.MaxFiles {
+		return false, nil
+	}
+	if w.MinPercentFilesChanged > 0 {
+		if cc.CountFiles == nil {
+			return false, fmt.Errorf("cannot count the files in %s", f.dir)
+		}
+		total, err := cc.CountFiles(f.dir)
+		if err != nil {
+			return false, fmt.Errorf("failed to count the files in %s: %w", f.dir, err)
+		}
+		if total == 0 || float64(filesInDir)*100/float64(total) <= w.MinPercentFilesChanged {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// relativeTo returns path relative to dir, and whether path is inside dir
+func relativeTo(dir string, path string) (string, bool) {
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if dir == "." || dir == "" {
+		return path, true
+	}
+	if !strings.HasPrefix(path, dir+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(path, dir+"/"), true
+}
@@ -0,0 +1,46 @@
+package notification
+
+import "fmt"
+
+type MergeMode string
+
+const (
+	// MergeModeAppend adds subscribers to the ones of parent directories
+	MergeModeAppend MergeMode = "append"
+	// MergeModeReplace ignores the subscribers of parent directories
+	MergeModeReplace MergeMode = "replace"
+)
+
+// Subscribers is a list of users or groups. In yaml it is either a plain list, which is appended to the subscribers
+// of parent directories, or a mapping like {mode: replace, values: [...]}.
+type Subscribers struct {
+	Mode   MergeMode `yaml:"mode,omitempty"`
+	Values []string  `yaml:"values,omitempty"`
+}
+
+func (s *Subscribers) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var values []string
+	if err := unmarshal(&values); err == nil {
+		s.Values = values
+		return nil
+	}
+	type plain Subscribers
+	return unmarshal((*plain)(s))
+}
+
+func (s Subscribers) MarshalYAML() (interface{}, error) {
+	if s.Mode == "" {
+		return s.Values, nil
+	}
+	type plain Subscribers
+	return plain(s), nil
+}
+
+func (s *Subscribers) validate(field string) []error {
+	switch s.Mode {
+	case MergeModeAppend, MergeModeReplace, "":
+		return nil
+	default:
+		return []error{fmt.Errorf("%s.mode: unknown merge mode %s", field, s.Mode)}
+	}
+}
@@ -0,0 +1,145 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/stringhelper"
+)
+
+// Subscriptions are the channels, users and groups a notification file notifies, across its sections and rules
+type Subscriptions struct {
+	Channels []string
+	Users    []string
+	Groups   []string
+}
+
+func (s *Subscriptions) addSections(sections *Sections) {
+	for _, section := range sections.all() {
+		n := section.notification
+		s.Channels = append(s.Channels, n.Channel)
+		s.Users = append(s.Users, n.Users.Values...)
+		s.Groups = append(s.Groups, n.Groups.Values...)
+		for _, c := range n.Channels {
+			s.Channels = append(s.Channels, c.Channel)
+			s.Users = append(s.Users, c.Users.Values...)
+			s.Groups = append(s.Groups, c.Groups.Values...)
+		}
+	}
+}
+
+// subscriptions returns everyone the file notifies, with its teams resolved
+func (f *File) subscriptions() Subscriptions {
+	var ret Subscriptions
+	ret.addSections(&f.Sections)
+	for idx := range f.Rules {
+		ret.addSections(&f.Rules[idx].Sections)
+	}
+	return ret.sorted()
+}
+
+// subscriptions returns everyone the routes notify, with their teams resolved
+func (r *RoutingFile) subscriptions() Subscriptions {
+	var ret Subscriptions
+	if r == nil {
+		return ret
+	}
+	for idx := range r.Routes {
+		ret.addSections(&r.Routes[idx].Sections)
+	}
+	return ret.sorted()
+}
+
+func (s Subscriptions) sorted() Subscriptions {
+	return Subscriptions{
+		Channels: sortedSet(s.Channels),
+		Users:    sortedSet(s.Users),
+		Groups:   sortedSet(s.Groups),
+	}
+}
+
+func sortedSet(values []string) []string {
+	ret := stringhelper.RemoveEmptyAndDeDup(values)
+	sort.Strings(ret)
+	return ret
+}
+
+// LoadSubscriptions returns everyone the notification file, or routing file, at path notifies, with the teams of the
+// root notification file resolved
+func (n *Loader) LoadSubscriptions(ctx context.Context, path string) (Subscriptions, error) {
+	root, err := n.LoadForPath(ctx, ".")
+	if err != nil {
+		return Subscriptions{}, fmt.Errorf("failed to load root notification file: %w", err)
+	}
+	if filepath.ToSlash(filepath.Clean(path)) == routingFile {
+		routes, err := n.LoadRoutingFile(ctx)
+		if err != nil {
+			return Subscriptions{}, err
+		}
+		if err := routes.resolveTeams(root.Teams); err != nil {
+			return Subscriptions{}, fmt.Errorf("failed to resolve teams for %s: %w", routingFile, err)
+		}
+		return routes.subscriptions(), nil
+	}
+	dir := filepath.Dir(path)
+	f, err := n.LoadForPath(ctx, dir)
+	if err != nil {
+		return Subscriptions{}, err
+	}
+	if err := f.resolveTeams(root.Teams); err != nil {
+		return Subscriptions{}, fmt.Errorf("failed to resolve teams for path %s: %w", dir, err)
+	}
+	return f.subscriptions(), nil
+}
+
+// SubscriptionChange is who a change to a notification file adds to or removes from its notifications
+type SubscriptionChange struct {
+	// Path of the notification file
+	Path            string
+	AddedChannels   []string
+	RemovedChannels []string
+	AddedUsers      []string
+	RemovedUsers    []string
+	AddedGroups     []string
+	RemovedGroups   []string
+	// Channels of both versions of the file, which are told about the change
+	Channels []string
+}
+
+// IsEmpty returns true if the change does not add or remove anyone
+func (s SubscriptionChange) IsEmpty() bool {
+	return len(s.AddedChannels)+len(s.RemovedChannels)+len(s.AddedUsers)+len(s.RemovedUsers)+len(s.AddedGroups)+len(s.RemovedGroups) == 0
+}
+
+// DiffSubscriptions returns who the notification file at path adds or removes between its before and after versions
+func DiffSubscriptions(path string, before Subscriptions, after Subscriptions) SubscriptionChange {
+	return SubscriptionChange{
+		Path:            path,
+		AddedChannels:   difference(after.Channels, before.Channels),
+		RemovedChannels: difference(before.Channels, after.Channels),
+		AddedUsers:      difference(after.Users, before.Users),
+		RemovedUsers:    difference(before.Users, after.Users),
+		AddedGroups:     difference(after.Groups, before.Groups),
+		RemovedGroups:   difference(before.Groups, after.Groups),
+		Channels:        sortedSet(append(append([]string{}, before.Channels...), after.Channels...)),
+	}
+}
+
+// difference returns the values of a that are not in b
+func difference(a []string, b []string) []string {
+	var ret []string
+	for _, value := range a {
+		if !stringhelper.Contains(b, value) {
+			ret = append(ret, value)
+		}
+	}
+	return ret
+}
+
+// HasSubscriptions returns true if the file at path lists who is notified: a notification file or the routing file
+func HasSubscriptions(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	return filepath.Base(path) == notificationFile || path == routingFile
+}
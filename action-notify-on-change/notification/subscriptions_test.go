@@ -0,0 +1,29 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSubscriptions(t *testing.T) {
+	before := &File{
+		Sections: Sections{PullRequest: Notification{Channel: "#db", Users: Subscribers{Values: []string{"a@example.com", "b@example.com"}}}},
+	}
+	after := &File{
+		Sections: Sections{PullRequest: Notification{Channel: "#db", Users: Subscribers{Values: []string{"a@example.com"}}}},
+		Rules: []Rule{
+			{Paths: []string{"**/*.sql"}, Sections: Sections{Commit: Notification{Channel: "#sql", Groups: Subscribers{Values: []string{"dba"}}}}},
+		},
+	}
+	change := DiffSubscriptions("db/.action-notify-on-change.yaml", before.subscriptions(), after.subscriptions())
+	assert.Equal(t, SubscriptionChange{
+		Path:          "db/.action-notify-on-change.yaml",
+		AddedChannels: []string{"#sql"},
+		RemovedUsers:  []string{"b@example.com"},
+		AddedGroups:   []string{"dba"},
+		Channels:      []string{"#db", "#sql"},
+	}, change)
+	assert.False(t, change.IsEmpty())
+	assert.True(t, DiffSubscriptions("a", after.subscriptions(), after.subscriptions()).IsEmpty())
+}
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"fmt"
+)
+
+// Team is a reusable set of subscribers, defined once under teams: in the notification file at the root of the
+// repository and referenced by name with team: in any notification
+type Team struct {
+	Users   []string `yaml:"users,omitempty"`
+	Groups  []string `yaml:"groups,omitempty"`
+	Channel string   `yaml:"channel,omitempty"`
+}
+
+// resolveTeams adds the subscribers of the team referenced by each notification to that notification. The team's
+// channel is used when the notification does not set one.
+func (f *File) resolveTeams(teams map[string]Team) error {
+	if err := f.Sections.resolveTeams(teams); err != nil {
+		return err
+	}
+	for idx := range f.Rules {
+		if err := f.Rules[idx].Sections.resolveTeams(teams); err != nil {
+			return fmt.Errorf("rules[%d]: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+func (r *RoutingFile) resolveTeams(teams map[string]Team) error {
+	if r == nil {
+		return nil
+	}
+	for idx := range r.Routes {
+		if err := r.Routes[idx].Sections.resolveTeams(teams); err != nil {
+			return fmt.Errorf("routes[%d]: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+func (s *Sections) resolveTeams(teams map[string]Team) error {
+	for _, section := range s.all() {
+		n := section.notification
+		if err := resolveTeam(teams, n.Team, &n.Channel, &n.Users, &n.Groups); err != nil {
+			return fmt.Errorf("%s: %w", section.name, err)
+		}
+		for idx := range n.Channels {
+			c := &n.Channels[idx]
+			if err := resolveTeam(teams, c.Team, &c.Channel, &c.Users, &c.Groups); err != nil {
+				return fmt.Errorf("%s.channels[%d]: %w", section.name, idx, err)
+			}
+		}
+	}
+	return nil
+}
+
+func resolveTeam(teams map[string]Team, name string, channel *string, users *Subscribers, groups *Subscribers) error {
+	if name == "" {
+		return nil
+	}
+	team, exists := teams[name]
+	if !exists {
+		return fmt.Errorf("unknown team %s", name)
+	}
+	if *channel == "" {
+		*channel = team.Channel
+	}
+	users.Values = append(users.Values, team.Users...)
+	groups.Values = append(groups.Values, team.Groups...)
+	return nil
+}
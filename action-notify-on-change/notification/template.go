@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/slack-go/slack/slackutilsx"
+)
+
+// TemplateContext is the data messageTemplate is executed with
+type TemplateContext struct {
+	// File is the notification file whose template is executed. Templates used to be executed with the file itself,
+	// so it is embedded to keep .ChangedFile, .PullRequest and its other fields working.
+	*File
+	// PrettyName of the notification file whose template is executed
+	PrettyName []string
+	// ParentMessage is the combined message of the parents of the notification file
+	ParentMessage string
+	// Title of the pull request, or first line of the commit message
+	Title string
+	// GitHub login of the author of the pull request or commit
+	Author     string
+	AuthorLink string
+	// Link to the pull request or commit
+	Link string
+	// Branch the pull request targets, or the branch the commit was pushed to
+	BaseBranch string
+	// Branch the pull request comes from
+	HeadBranch        string
+	PullRequestNumber int
+	CommitSha         string
+	// Which section of the notification file applies: pullRequest, commit, opened, merged, ...
+	Event string
+	// The changed files the notification applies to, with their status and line counts
+	Files []ghclient.ChangedFile
+	// Lines added and removed in Files
+	Additions int
+	Deletions int
+}
+
+// WithFiles returns a copy of the context for files, with their line counts added up
+func (t TemplateContext) WithFiles(files []ghclient.ChangedFile) TemplateContext {
+	t.Files = files
+	t.Additions, t.Deletions = 0, 0
+	for _, file := range files {
+		t.Additions += file.Additions
+		t.Deletions += file.Deletions
+	}
+	return t
+}
+
+// templateFuncs are the functions message templates can use, on top of the builtin template functions
+var templateFuncs = template.FuncMap{
+	// join ", " .PrettyName
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+	// truncate 50 .Title cuts the string to at most 50 characters, ending with … when it was cut
+	"truncate": func(length int, s string) string {
+		runes := []rune(s)
+		if length <= 0 || len(runes) <= length {
+			return s
+		}
+		if length == 1 {
+			return "…"
+		}
+		return string(runes[:length-1]) + "…"
+	},
+	// pluralize (len .Files) "file" "files" returns "1 file" or "3 files"
+	"pluralize": func(count int, singular string, plural string) string {
+		if count == 1 {
+			return fmt.Sprintf("%d %s", count, singular)
+		}
+		return fmt.Sprintf("%d %s", count, plural)
+	},
+	// slackEscape .Title escapes &, < and > so user provided text cannot add links or mentions
+	"slackEscape": slackutilsx.EscapeMessage,
+	// mrkdwnLink .Link .Title renders a Slack link with escaped text
+	"mrkdwnLink": mrkdwnLink,
+	// slackLink is the same as mrkdwnLink
+	"slackLink": mrkdwnLink,
+	// userMention "U012AB3CD" mentions a Slack user by member ID
+	"userMention": func(userID string) string {
+		return fmt.Sprintf("<@%s>", userID)
+	},
+	// regexReplace "^feature/" "" .HeadBranch
+	"regexReplace": func(pattern string, replacement string, s string) (string, error) {
+		rgx, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex %s: %w", pattern, err)
+		}
+		return rgx.ReplaceAllString(s, replacement), nil
+	},
+	// toJSON .Title quotes a value as JSON, to safely put text inside a blocks template
+	"toJSON": func(v interface{}) (string, error) {
+		ret, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert to JSON: %w", err)
+		}
+		return string(ret), nil
+	},
+}
+
+func mrkdwnLink(url string, text string) string {
+	return fmt.Sprintf("<%s|%s>", url, slackutilsx.EscapeMessage(text))
+}
+
+// executeTemplate executes messageTemplate with data and converts its output to Slack mrkdwn if needed
+func executeTemplate(messageTemplate string, format MessageFormat, data TemplateContext) (string, error) {
+	t, err := newTemplate(messageTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", messageTemplate, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute template %s: %w", messageTemplate, err)
+	}
+	if format == MessageFormatMarkdown {
+		return markdownToMrkdwn(b.String()), nil
+	}
+	return b.String(), nil
+}
+
+func newTemplate(messageTemplate string) (*template.Template, error) {
+	return template.New("message").Funcs(templateFuncs).Parse(messageTemplate)
+}
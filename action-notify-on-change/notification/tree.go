@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+)
+
+// treeContents are the configuration files of the repository, read all at once from the list of its files
+type treeContents struct {
+	ghClient *ghclient.GhClient
+	// files is every file of the repository
+	files map[string]struct{}
+	// configs are the contents of the notification, routing and ignore files
+	configs map[string][]byte
+}
+
+// loadTree lists the files of the repository and reads every configuration file among them, once
+func (n *Loader) loadTree(ctx context.Context) (*treeContents, error) {
+	n.treeOnce.Do(func() {
+		files, err := n.ghClient.ListFiles(ctx)
+		if err != nil {
+			n.treeErr = fmt.Errorf("failed to list files: %w", err)
+			return
+		}
+		ret := &treeContents{
+			ghClient: n.ghClient,
+			files:    make(map[string]struct{}, len(files)),
+		}
+		var configPaths []string
+		for _, file := range files {
+			ret.files[file] = struct{}{}
+			if path.Base(file) == notificationFile || file == routingFile || file == ignoreFile {
+				configPaths = append(configPaths, file)
+			}
+		}
+		n.logger.Debugf("reading %d configuration files", len(configPaths))
+		ret.configs, err = n.ghClient.GetBlobs(ctx, configPaths)
+		if err != nil {
+			n.treeErr = fmt.Errorf("failed to read configuration files: %w", err)
+			return
+		}
+		n.tree = ret
+	})
+	return n.tree, n.treeErr
+}
+
+// getContents returns the content of the file at filePath, or nil if the repository does not have it. Only files
+// that are not configuration files, like included ones, are read from GitHub.
+func (t *treeContents) getContents(ctx context.Context, filePath string) ([]byte, error) {
+	filePath = filepath.ToSlash(filepath.Clean(filePath))
+	if content, exists := t.configs[filePath]; exists {
+		return content, nil
+	}
+	if _, exists := t.files[filePath]; !exists {
+		return nil, nil
+	}
+	return t.ghClient.GetContents(ctx, filePath)
+}
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v2"
+)
+
+var yamlSyntaxErrorLine = regexp.MustCompile(`^yaml: line ([0-9]+): (.*)$`)
+
+// decodeStrict unmarshals content into out, failing on unknown keys. Errors are reported as filePath:line: message
+// so a typo in a notification file fails loudly instead of silently dropping notifications.
+func decodeStrict(filePath string, content []byte, out interface{}) error {
+	err := yaml.UnmarshalStrict(content, out)
+	if err == nil {
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs := make([]string, 0, len(typeErr.Errors))
+		for _, e := range typeErr.Errors {
+			msgs = append(msgs, fmt.Sprintf("%s:%s", filePath, strings.TrimPrefix(e, "line ")))
+		}
+		return fmt.Errorf("invalid notification file:\n%s", strings.Join(msgs, "\n"))
+	}
+	if matches := yamlSyntaxErrorLine.FindStringSubmatch(err.Error()); len(matches) == 3 {
+		return fmt.Errorf("invalid yaml:\n%s:%s: %s", filePath, matches[1], matches[2])
+	}
+	return fmt.Errorf("invalid yaml in %s: %w", filePath, err)
+}
+
+// Validate checks the parts of a notification file that yaml decoding cannot, like globs and templates
+func (f *File) Validate() error {
+	var errs []error
+	errs = append(errs, f.Sections.validate("")...)
+	errs = append(errs, validateTemplate("messageTemplate", f.MessageTemplate)...)
+	errs = append(errs, validateFormat("format", f.Format)...)
+	errs = append(errs, validateMessageMode("messageMode", f.MessageMode)...)
+	errs = append(errs, validateTemplate("blocksTemplate", f.BlocksTemplate)...)
+	errs = append(errs, validateGlobs("ignore", f.Ignore)...)
+	for idx, include := range f.Include {
+		if include == "" {
+			errs = append(errs, fmt.Errorf("include[%d]: empty path", idx))
+		}
+	}
+	for idx := range f.Rules {
+		errs = append(errs, f.Rules[idx].validate(fmt.Sprintf("rules[%d]", idx))...)
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the globs and templates of every route
+func (r *RoutingFile) Validate() error {
+	var errs []error
+	switch r.Mode {
+	case RoutingModeLastMatch, RoutingModeMerge, "":
+	default:
+		errs = append(errs, fmt.Errorf("mode: unknown routing mode %s", r.Mode))
+	}
+	for idx := range r.Routes {
+		errs = append(errs, r.Routes[idx].validate(fmt.Sprintf("routes[%d]", idx))...)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Rule) validate(field string) []error {
+	var errs []error
+	if len(r.Paths) == 0 && len(r.PathRegex) == 0 {
+		errs = append(errs, fmt.Errorf("%s: one of paths or pathRegex is required", field))
+	}
+	errs = append(errs, validateGlobs(field+".paths", r.Paths)...)
+	errs = append(errs, validateGlobs(field+".exclude", r.Exclude)...)
+	errs = append(errs, validateRegexes(field+".pathRegex", r.PathRegex)...)
+	errs = append(errs, validateRegexes(field+".excludeRegex", r.ExcludeRegex)...)
+	errs = append(errs, r.Sections.validate(field)...)
+	errs = append(errs, validateTemplate(field+".messageTemplate", r.MessageTemplate)...)
+	errs = append(errs, validateFormat(field+".format", r.Format)...)
+	return errs
+}
+
+func (n *Notification) validate(field string) []error {
+	var errs []error
+	errs = append(errs, n.Users.validate(field+".users")...)
+	errs = append(errs, n.Groups.validate(field+".groups")...)
+	errs = append(errs, n.When.validate(field+".when")...)
+	errs = append(errs, validateRegexes(field+".contentMatch", n.ContentMatch)...)
+	for idx := range n.Channels {
+		errs = append(errs, n.Channels[idx].validate(fmt.Sprintf("%s.channels[%d]", field, idx))...)
+	}
+	switch n.GeneratedFiles {
+	case GeneratedFilesInclude, GeneratedFilesCollapse, GeneratedFilesSkip, "":
+	default:
+		errs = append(errs, fmt.Errorf("%s.generatedFiles: unknown mode %s", field, n.GeneratedFiles))
+	}
+	errs = append(errs, validateTemplate(field+".messageTemplate", n.MessageTemplate)...)
+	errs = append(errs, validateFormat(field+".format", n.Format)...)
+	return errs
+}
+
+func (c *ChannelTarget) validate(field string) []error {
+	var errs []error
+	if c.Channel == "" && c.Team == "" {
+		errs = append(errs, fmt.Errorf("%s: one of channel or team is required", field))
+	}
+	errs = append(errs, c.Users.validate(field+".users")...)
+	errs = append(errs, c.Groups.validate(field+".groups")...)
+	errs = append(errs, validateTemplate(field+".messageTemplate", c.MessageTemplate)...)
+	errs = append(errs, validateFormat(field+".format", c.Format)...)
+	return errs
+}
+
+func validateGlobs(field string, globs []string) []error {
+	var errs []error
+	for idx, glob := range globs {
+		if !doublestar.ValidatePattern(glob) {
+			errs = append(errs, fmt.Errorf("%s[%d]: invalid glob %s", field, idx, glob))
+		}
+	}
+	return errs
+}
+
+func validateRegexes(field string, regexes []string) []error {
+	var errs []error
+	for idx, rgx := range regexes {
+		if _, err := regexp.Compile(rgx); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", field, idx, err))
+		}
+	}
+	return errs
+}
+
+func validateTemplate(field string, messageTemplate string) []error {
+	if messageTemplate == "" {
+		return nil
+	}
+	if _, err := newTemplate(messageTemplate); err != nil {
+		return []error{fmt.Errorf("%s: %w", field, err)}
+	}
+	return nil
+}
+
+func validateFormat(field string, format MessageFormat) []error {
+	switch format {
+	case MessageFormatMrkdwn, MessageFormatMarkdown, "":
+		return nil
+	default:
+		return []error{fmt.Errorf("%s: unknown format %s", field, format)}
+	}
+}
+
+func validateMessageMode(field string, mode MessageMode) []error {
+	switch mode {
+	case MessageModeAppend, MessageModePrepend, MessageModeReplace, MessageModeSection, "":
+		return nil
+	default:
+		return []error{fmt.Errorf("%s: unknown message mode %s", field, mode)}
+	}
+}
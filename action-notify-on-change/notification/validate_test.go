@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	run := func(content string, expectedErr string) func(t *testing.T) {
+		return func(t *testing.T) {
+			var f File
+			err := decodeStrict("svc/.action-notify-on-change.yaml", []byte(content), &f)
+			if expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), expectedErr)
+		}
+	}
+	t.Run("valid", run("pullRequest:\n  channel: '#svc'\n", ""))
+	t.Run("typo", run("pullRequest:\n  chanel: '#svc'\n", "svc/.action-notify-on-change.yaml:2: field chanel not found"))
+	t.Run("unknown section", run("pullrequest:\n  channel: '#svc'\n", "svc/.action-notify-on-change.yaml:1: field pullrequest not found"))
+	t.Run("syntax", run("pullRequest:\n  channel: x\n bad: : y\n", "svc/.action-notify-on-change.yaml:2:"))
+}
+
+func TestValidate(t *testing.T) {
+	f := File{Rules: []Rule{{Paths: []string{"[a"}}, {PathRegex: []string{"("}}, {}}}
+	err := f.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rules[0].paths[0]: invalid glob")
+	assert.Contains(t, err.Error(), "rules[1].pathRegex[0]")
+	assert.Contains(t, err.Error(), "rules[2]: one of paths or pathRegex is required")
+}
+
+// TestSchemaMatchesTypes makes sure schema.json is updated when a field is added to a notification file
+func TestSchemaMatchesTypes(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(JSONSchema, &schema))
+	for _, typ := range []interface{}{File{}, Notification{}, ChannelTarget{}, When{}, Team{}, Rule{}, RoutingFile{}} {
+		rt := reflect.TypeOf(typ)
+		def, exists := schema.Defs[rt.Name()]
+		require.True(t, exists, "schema is missing %s", rt.Name())
+		schemaKeys := make([]string, 0, len(def.Properties))
+		for key := range def.Properties {
+			schemaKeys = append(schemaKeys, key)
+		}
+		sort.Strings(schemaKeys)
+		assert.Equal(t, yamlKeys(rt), schemaKeys, "schema properties of %s", rt.Name())
+	}
+}
+
+func yamlKeys(rt reflect.Type) []string {
+	ret := make([]string, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("yaml")
+		if tag == ",inline" {
+			ret = append(ret, yamlKeys(rt.Field(i).Type)...)
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
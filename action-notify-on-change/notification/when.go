@@ -0,0 +1,161 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/config"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/ghclient"
+	"github.com/cresta/action-notify-on-change/action-notify-on-change/stringhelper"
+)
+
+// ChangeContext is what the when block of a notification is evaluated against
+type ChangeContext struct {
+	// Branch a pull request targets, or the branch a commit was pushed to
+	BaseBranch string
+	// Branch a pull request comes from, or the branch a commit was pushed to
+	HeadBranch  string
+	Author      string
+	AuthorIsBot bool
+	// How the changed file changed: added, removed, modified, renamed, copied, changed or unchanged
+	FileStatus string
+	// Unified diff of the changed file
+	Patch string
+	// Every file changed by the pull request or commit
+	AllFiles []ghclient.ChangedFile
+	// CountFiles returns how many files are under a directory of the repository
+	CountFiles func(dir string) (int, error)
+}
+
+// When limits a notification to some changes. Every condition that is set must match.
+type When struct {
+	// Glob patterns, like release/*, of the branch a pull request targets
+	BaseBranches []string `yaml:"baseBranches,omitempty"`
+	// Glob patterns of the branch a pull request comes from
+	HeadBranches []string `yaml:"headBranches,omitempty"`
+	// Only notify for changes by these GitHub logins
+	Authors []string `yaml:"authors,omitempty"`
+	// Never notify for changes by these GitHub logins
+	ExcludeAuthors []string `yaml:"excludeAuthors,omitempty"`
+	// If false, never notify for changes by bots. If true, only notify for changes by bots.
+	Bots *bool `yaml:"bots,omitempty"`
+	// Only notify for files that were, for example, added or removed
+	Statuses []string `yaml:"statuses,omitempty"`
+	// Only notify when at least this many lines were added or removed in the files the notification applies to
+	MinLinesChanged int `yaml:"minLinesChanged,omitempty"`
+	// Only notify when at most this many of the files the notification applies to changed
+	MaxFiles int `yaml:"maxFiles,omitempty"`
+	// Only notify when more than this percentage of the files under the directory changed
+	MinPercentFilesChanged float64 `yaml:"minPercentFilesChanged,omitempty"`
+}
+
+// fileStatuses are the statuses GitHub gives to changed files
+var fileStatuses = []string{"added", "removed", "modified", "renamed", "copied", "changed", "unchanged"}
+
+func (w *When) Matches(cc ChangeContext) (bool, error) {
+	if w == nil {
+		return true, nil
+	}
+	if ok, err := matchesBranch(w.BaseBranches, cc.BaseBranch); err != nil || !ok {
+		return false, err
+	}
+	if ok, err := matchesBranch(w.HeadBranches, cc.HeadBranch); err != nil || !ok {
+		return false, err
+	}
+	if len(w.Authors) > 0 && !containsLogin(w.Authors, cc.Author) {
+		return false, nil
+	}
+	if containsLogin(w.ExcludeAuthors, cc.Author) {
+		return false, nil
+	}
+	if w.Bots != nil && *w.Bots != cc.IsBot() {
+		return false, nil
+	}
+	if len(w.Statuses) > 0 && !stringhelper.Contains(w.Statuses, cc.FileStatus) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// IsBot returns true if the author is a GitHub app, like dependabot[bot] or renovate[bot]
+func (cc ChangeContext) IsBot() bool {
+	return cc.AuthorIsBot || strings.HasSuffix(cc.Author, "[bot]")
+}
+
+func matchesBranch(globs []string, branch string) (bool, error) {
+	if len(globs) == 0 {
+		return true, nil
+	}
+	for _, glob := range globs {
+		matched, err := doublestar.Match(glob, branch)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob %s: %w", glob, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsLogin(logins []string, login string) bool {
+	for _, l := range logins {
+		if strings.EqualFold(l, login) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *When) validate(field string) []error {
+	if w == nil {
+		return nil
+	}
+	var errs []error
+	errs = append(errs, validateGlobs(field+".baseBranches", w.BaseBranches)...)
+	errs = append(errs, validateGlobs(field+".headBranches", w.HeadBranches)...)
+	if w.MinLinesChanged < 0 || w.MaxFiles < 0 || w.MinPercentFilesChanged < 0 || w.MinPercentFilesChanged > 100 {
+		errs = append(errs, fmt.Errorf("%s: size conditions must be positive, and percentages at most 100", field))
+	}
+	for idx, status := range w.Statuses {
+		if !stringhelper.Contains(fileStatuses, status) {
+			errs = append(errs, fmt.Errorf("%s.statuses[%d]: unknown status %s, expected one of %v", field, idx, status, fileStatuses))
+		}
+	}
+	return errs
+}
+
+// WithConditions returns a copy of the file, and its parents, where sections whose when block does not match cc
+// are ignored
+func (f *File) WithConditions(changeType config.ChangeType, cc ChangeContext) (*File, error) {
+	if f == nil {
+		return nil, nil
+	}
+	parent, err := f.Parent.WithConditions(changeType, cc)
+	if err != nil {
+		return nil, err
+	}
+	ret := *f
+	ret.Parent = parent
+	section := f.section(changeType)
+	matched, err := section.When.Matches(cc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate when of %s: %w", f.ChangedFile, err)
+	}
+	if matched && section.When.hasSizeConditions() {
+		matched, err = f.matchesSize(section.When, cc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate size conditions of %s: %w", f.ChangedFile, err)
+		}
+	}
+	if matched && len(section.ContentMatch) > 0 {
+		ret.matchedLines, err = matchPatch(section.ContentMatch, cc.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate contentMatch of %s: %w", f.ChangedFile, err)
+		}
+		matched = len(ret.matchedLines) > 0
+	}
+	ret.conditionsFailed = !matched
+	return &ret, nil
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhenStatuses(t *testing.T) {
+	run := func(when *When, status string, expected bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			matched, err := when.Matches(ChangeContext{FileStatus: status})
+			require.NoError(t, err)
+			assert.Equal(t, expected, matched)
+		}
+	}
+	addedOrRemoved := &When{Statuses: []string{"added", "removed"}}
+	t.Run("no when", run(nil, "modified", true))
+	t.Run("no statuses", run(&When{}, "modified", true))
+	t.Run("added", run(addedOrRemoved, "added", true))
+	t.Run("removed", run(addedOrRemoved, "removed", true))
+	t.Run("modified", run(addedOrRemoved, "modified", false))
+	t.Run("renamed", run(addedOrRemoved, "renamed", false))
+}
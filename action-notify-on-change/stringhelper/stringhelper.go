@@ -12,6 +12,7 @@ func Deduplicate(strings []string) []string {
 	}
 	return ret
 }
+
 func RemoveEmptyAndDeDup(split []string) []string {
 	ret := make([]string, 0, len(split))
 	for _, s := range split {
@@ -21,3 +22,12 @@ func RemoveEmptyAndDeDup(split []string) []string {
 	}
 	return Deduplicate(ret)
 }
+
+func Contains(strings []string, s string) bool {
+	for _, str := range strings {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}